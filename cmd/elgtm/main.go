@@ -2,30 +2,106 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fzl-22/elgtm/internal/bootstrap"
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/logger"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/spf13/pflag"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd command, args []string) int
+}
+
+var commands = []command{
+	{name: "review", usage: "review [flags]", summary: "Review the pull request and post the review (default)", run: runReview},
+	{name: "render", usage: "render [flags]", summary: "Print the prompt of the review without calling the LLM", run: runRender},
+	{name: "validate", usage: "validate [flags]", summary: "Check the configuration without reviewing", run: runValidate},
+	{name: "prompts", usage: "prompts list [flags]", summary: "List the available prompts", run: runPrompts},
+	{name: "version", usage: "version", summary: "Print the version", run: runVersion},
+}
+
 func main() {
-	os.Exit(run())
+	os.Exit(run(os.Args[1:]))
 }
 
-func run() int {
-	cfg, err := config.NewConfig()
-	if err != nil {
-		slog.Error("Config load failed", "error", err)
-		return 1
+// Exit codes of the commands.
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+	exitConfig = 3
+)
+
+// run runs the command named by the first argument, or a review when the
+// arguments start with a flag, and returns the exit code: exitFailed when the
+// command fails, exitUsage on usage errors and exitConfig when the
+// configuration cannot be loaded or is invalid.
+func run(args []string) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		printUsage(stdout)
+		return exitOK
 	}
 
-	logger.Setup(cfg.System.LogLevel)
+	name := "review"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(cmd, args)
+		}
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+	printUsage(stderr)
+	return exitUsage
+}
+
+func runReview(cmd command, args []string) int {
+	cfg, code := loadConfig(cmd, args)
+	if cfg == nil {
+		return code
+	}
+
+	return review(cfg)
+}
 
+func runRender(cmd command, args []string) int {
+	cfg, code := loadConfig(cmd, args)
+	if cfg == nil {
+		return code
+	}
+
+	cfg.Review.RenderOnly = true
+	return review(cfg)
+}
+
+func review(cfg *config.Config) int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -34,23 +110,212 @@ func run() int {
 	defer cancel()
 
 	slog.Info("Starting ELGTM",
+		"version", versionString(),
 		"scm_platform", cfg.SCM.Platform,
 		"llm_provider", cfg.LLM.Provider,
 		"system_log_level", cfg.System.LogLevel,
 		"system_timeout", timeoutDuration.String(),
+		"dry_run", cfg.Review.DryRun,
+		"render_only", cfg.Review.RenderOnly,
 	)
 
+	if code := validateConfig(cfg); code != exitOK {
+		return code
+	}
+
 	engine, err := bootstrap.Initialize(ctx, cfg)
 	if err != nil {
 		slog.Error("Initialization failed", "error", err)
-		return 1
+		return exitFailed
 	}
 
 	if err := engine.Run(ctx); err != nil {
 		slog.Error("Review failed", "error", err)
-		return 1
+		return exitFailed
 	}
 
 	slog.Info("Review completed successfully")
-	return 0
+	return exitOK
+}
+
+func runValidate(cmd command, args []string) int {
+	cfg, code := loadConfig(cmd, args)
+	if cfg == nil {
+		return code
+	}
+
+	if code := validateConfig(cfg); code != exitOK {
+		return code
+	}
+
+	engine, err := bootstrap.Initialize(context.Background(), cfg)
+	if err != nil {
+		slog.Error("Initialization failed", "error", err)
+		return exitFailed
+	}
+
+	promptPath, err := engine.ResolvePromptPath(cfg.Review.PromptDir, cfg.Review.PromptType)
+	if err != nil {
+		slog.Error("Prompt resolution failed", "error", err)
+		return exitFailed
+	}
+
+	fmt.Fprintf(stdout, "Configuration is valid (%s via %s, %s/%s, prompt %s)\n",
+		cfg.SCM.Platform, cfg.LLM.Provider, cfg.LLM.Provider, cfg.LLM.Model, promptPath)
+	return exitOK
+}
+
+func runPrompts(cmd command, args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			printCommandUsage(stdout, cmd, newFlagSet(cmd))
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "usage: elgtm %s\n", cmd.usage)
+		return exitUsage
+	}
+
+	cfg, code := loadConfig(cmd, args[1:])
+	if cfg == nil {
+		return code
+	}
+
+	prompts, err := reviewer.ListPrompts(cfg.Review.PromptDir)
+	if err != nil {
+		slog.Error("Failed to list prompts", "error", err)
+		return exitFailed
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tSOURCE\tPATH")
+	for _, prompt := range prompts {
+		source := "repository"
+		if prompt.System {
+			source = "built-in"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", prompt.Type, source, prompt.Path)
+	}
+	w.Flush()
+
+	return exitOK
+}
+
+func runVersion(cmd command, args []string) int {
+	fmt.Fprintf(stdout, "elgtm %s\n", versionString())
+	return exitOK
+}
+
+// versionString returns the version set at build time, or the module version
+// of binaries built with go install.
+func versionString() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// loadConfig parses the flags of the command and loads the configuration they
+// override, then sets up logging. It returns a nil config along with the exit
+// code when the command should stop instead, after printing its help or a
+// usage error.
+func loadConfig(cmd command, args []string) (*config.Config, int) {
+	flags := newFlagSet(cmd)
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			printCommandUsage(stdout, cmd, flags)
+			return nil, exitOK
+		}
+		fmt.Fprintf(stderr, "%v\n\n", err)
+		printCommandUsage(stderr, cmd, flags)
+		return nil, exitUsage
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n\n", strings.Join(flags.Args(), " "))
+		printCommandUsage(stderr, cmd, flags)
+		return nil, exitUsage
+	}
+
+	cfg, err := config.NewConfig(flags)
+	if err != nil {
+		slog.Error("Config load failed", "error", err)
+		return nil, exitConfig
+	}
+
+	logger.Setup(cfg.System.LogLevel)
+
+	for _, source := range cfg.Sources() {
+		slog.Debug("Config value resolved", "key", source.Key, "source", source.Origin)
+	}
+
+	return cfg, exitOK
+}
+
+// validateConfig logs every problem of an invalid configuration and returns
+// exitConfig, or exitOK when the configuration is valid.
+func validateConfig(cfg *config.Config) int {
+	err := cfg.Validate()
+
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		slog.Error("Invalid configuration", "problems", verr.Problems)
+		return exitConfig
+	}
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return exitConfig
+	}
+
+	return exitOK
+}
+
+func newFlagSet(cmd command) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(cmd.name, pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	config.BindFlags(flags)
+	return flags
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "ELGTM reviews pull requests with an LLM.\n\nUsage:\n  elgtm [command] [flags]\n\nCommands:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	printFlags(w, newFlagSet(commands[0]))
+}
+
+func printCommandUsage(w io.Writer, cmd command, flags *pflag.FlagSet) {
+	fmt.Fprintf(w, "%s.\n\nUsage:\n  elgtm %s\n\n", cmd.summary, cmd.usage)
+	printFlags(w, flags)
+}
+
+// printFlags lists every configuration key with its flag, environment
+// variable and default.
+func printFlags(w io.Writer, flags *pflag.FlagSet) {
+	fmt.Fprintf(w, "Flags override environment variables, which override defaults:\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FLAG\tENV\tDEFAULT\tDESCRIPTION")
+	for _, key := range config.Keys() {
+		flag := "--" + key.Flag
+		if f := flags.Lookup(key.Flag); f != nil {
+			if typ, _ := pflag.UnquoteUsage(f); typ != "" {
+				flag += " " + typ
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", flag, key.Env, key.Default, key.Usage)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n  -h, --help  Show this help\n")
 }
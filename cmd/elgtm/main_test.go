@@ -1,12 +1,29 @@
 package main
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// captureOutput redirects the output of the commands for the duration of the
+// test.
+func captureOutput(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	stdout, stderr = &out, &errOut
+	t.Cleanup(func() {
+		stdout, stderr = os.Stdout, os.Stderr
+	})
+
+	return &out, &errOut
+}
+
 func TestRun(t *testing.T) {
 	setEnv := func(t *testing.T, key, value string) {
 		t.Helper()
@@ -17,17 +34,40 @@ func TestRun(t *testing.T) {
 		os.Clearenv()
 		setEnv(t, "SCM_PR_NUMBER", "not-a-number")
 
-		exitCode := run()
+		exitCode := run(nil)
 
-		assert.Equal(t, 1, exitCode)
+		assert.Equal(t, exitConfig, exitCode)
 	})
 
-	t.Run("Failure_InitializationFailed", func(t *testing.T) {
+	t.Run("Failure_UnknownFlag", func(t *testing.T) {
+		os.Clearenv()
+
+		_, errOut := captureOutput(t)
+
+		exitCode := run([]string{"--no-such-flag"})
+
+		assert.Equal(t, 2, exitCode)
+		assert.Contains(t, errOut.String(), "unknown flag: --no-such-flag")
+	})
+
+	t.Run("Failure_InvalidConfig", func(t *testing.T) {
 		os.Clearenv()
 		setEnv(t, "SCM_PLATFORM", "github")
 		setEnv(t, "SCM_TOKEN", "")
 
-		exitCode := run()
+		exitCode := run(nil)
+
+		assert.Equal(t, exitConfig, exitCode)
+	})
+
+	t.Run("Failure_InitializationFailed", func(t *testing.T) {
+		os.Clearenv()
+		setEnv(t, "SCM_PLATFORM", "local")
+		setEnv(t, "SCM_LOCAL_DIR", t.TempDir()) // Not a git repository
+		setEnv(t, "LLM_PROVIDER", "ollama")
+		setEnv(t, "LLM_MODEL", "qwen2.5-coder")
+
+		exitCode := run(nil)
 
 		assert.Equal(t, 1, exitCode)
 	})
@@ -37,12 +77,125 @@ func TestRun(t *testing.T) {
 
 		setEnv(t, "SCM_PLATFORM", "github")
 		setEnv(t, "SCM_TOKEN", "dummy-token")
+		setEnv(t, "SCM_OWNER", "dummy-owner")
+		setEnv(t, "SCM_REPO", "dummy-repo")
+		setEnv(t, "SCM_PR_NUMBER", "1")
 		setEnv(t, "LLM_PROVIDER", "gemini")
+		setEnv(t, "LLM_MODEL", "gemini-2.5-flash")
 		setEnv(t, "LLM_API_KEY", "dummy-api-key")
 		setEnv(t, "REVIEW_PROMPT_TYPE", "this-prompt-does-not-exist")
 
-		exitCode := run()
+		exitCode := run(nil)
 
 		assert.Equal(t, 1, exitCode)
 	})
+
+	t.Run("Success_Help", func(t *testing.T) {
+		os.Clearenv()
+		out, _ := captureOutput(t)
+
+		exitCode := run([]string{"--help"})
+
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, out.String(), "prompts list [flags]")
+		assert.Regexp(t, `--llm-model string +LLM_MODEL +Model ID`, out.String())
+		assert.Regexp(t, `--scm-max-diff-size int +SCM_MAX_DIFF_SIZE +2097152 `, out.String())
+	})
+
+	t.Run("Success_CommandHelp", func(t *testing.T) {
+		os.Clearenv()
+		out, _ := captureOutput(t)
+
+		exitCode := run([]string{"render", "-h"})
+
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, out.String(), "elgtm render [flags]")
+		assert.Regexp(t, `--review-prompt-type string +REVIEW_PROMPT_TYPE +general `, out.String())
+	})
+
+	t.Run("Success_Version", func(t *testing.T) {
+		out, _ := captureOutput(t)
+
+		exitCode := run([]string{"version"})
+
+		assert.Equal(t, 0, exitCode)
+		assert.Equal(t, "elgtm dev\n", out.String())
+	})
+
+	t.Run("Success_ListPrompts", func(t *testing.T) {
+		os.Clearenv()
+		out, _ := captureOutput(t)
+
+		promptDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(promptDir, "security.md"), []byte("Review"), 0644))
+
+		exitCode := run([]string{"prompts", "list", "--review-prompt-dir", promptDir})
+
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, out.String(), "security  repository  "+filepath.Join(promptDir, "security.md"))
+	})
+
+	t.Run("Success_ValidateWithFlagsOverridingEnv", func(t *testing.T) {
+		os.Clearenv()
+		out, _ := captureOutput(t)
+
+		promptDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(promptDir, "general.md"), []byte("Review"), 0644))
+
+		setEnv(t, "SCM_PLATFORM", "gitlab")
+		setEnv(t, "LLM_PROVIDER", "gemini")
+
+		exitCode := run([]string{"validate",
+			"--scm-platform", "github",
+			"--scm-token", "dummy-token",
+			"--scm-owner", "dummy-owner",
+			"--scm-repo", "dummy-repo",
+			"--scm-pr-number", "1",
+			"--llm-provider", "ollama",
+			"--llm-model", "qwen2.5-coder",
+			"--review-prompt-dir", promptDir,
+		})
+
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, out.String(), "Configuration is valid (github via ollama")
+	})
+
+	t.Run("Failure_ValidateMutuallyExclusiveOptions", func(t *testing.T) {
+		os.Clearenv()
+		setEnv(t, "SCM_PLATFORM", "local")
+		setEnv(t, "LLM_PROVIDER", "ollama")
+		setEnv(t, "LLM_MODEL", "qwen2.5-coder")
+
+		exitCode := run([]string{"validate", "--dry-run", "--render-only"})
+
+		assert.Equal(t, exitConfig, exitCode)
+	})
+
+	t.Run("Failure_UnknownCommand", func(t *testing.T) {
+		_, errOut := captureOutput(t)
+
+		exitCode := run([]string{"publish"})
+
+		assert.Equal(t, 2, exitCode)
+		assert.Contains(t, errOut.String(), `unknown command "publish"`)
+	})
+
+	t.Run("Failure_PromptsWithoutSubcommand", func(t *testing.T) {
+		_, errOut := captureOutput(t)
+
+		exitCode := run([]string{"prompts"})
+
+		assert.Equal(t, 2, exitCode)
+		assert.Contains(t, errOut.String(), "usage: elgtm prompts list [flags]")
+	})
+
+	t.Run("Failure_UnexpectedArguments", func(t *testing.T) {
+		os.Clearenv()
+		_, errOut := captureOutput(t)
+
+		exitCode := run([]string{"review", "123"})
+
+		assert.Equal(t, 2, exitCode)
+		assert.Contains(t, errOut.String(), "unexpected arguments: 123")
+	})
 }
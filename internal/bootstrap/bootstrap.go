@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/llm"
 	"github.com/fzl-22/elgtm/internal/reviewer"
 	"github.com/fzl-22/elgtm/internal/scm"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
 func Initialize(ctx context.Context, cfg *config.Config) (*reviewer.Engine, error) {
@@ -19,12 +21,34 @@ func Initialize(ctx context.Context, cfg *config.Config) (*reviewer.Engine, erro
 	var err error
 
 	// Initialize SCM
+	scmHTTPClient, err := scm.NewHTTPClient(&httpClient, cfg.SCM.CACertFile, cfg.SCM.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SCM driver: %w", err)
+	}
+
 	var scmDriver scm.Driver
 	switch cfg.SCM.Platform {
 	case config.PlatformGitHub:
-		scmDriver, err = scm.NewGitHubDriver(&httpClient, cfg.SCM.Token)
+		var opts []scm.GitHubOption
+		if cfg.SCM.BaseURL != "" {
+			opts = append(opts, scm.WithEnterpriseURLs(cfg.SCM.BaseURL, cfg.SCM.UploadURL))
+		}
+		scmDriver, err = newGitHubDriver(scmHTTPClient, cfg.SCM, opts)
 	case config.PlatformGitLab:
-		scmDriver, err = scm.NewGitLabDriver(cfg.SCM.Token)
+		opts := []gitlab.ClientOptionFunc{gitlab.WithHTTPClient(scmHTTPClient)}
+		if cfg.SCM.BaseURL != "" {
+			opts = append(opts, gitlab.WithBaseURL(cfg.SCM.BaseURL))
+		}
+		scmDriver, err = scm.NewGitLabDriver(cfg.SCM.Token.Value(), opts...)
+	case config.PlatformBitbucket:
+		scmDriver, err = scm.NewBitbucketDriver(scmHTTPClient, cfg.SCM.BaseURL, cfg.SCM.Username, cfg.SCM.Token.Value())
+	case config.PlatformGitea:
+		scmDriver, err = scm.NewGiteaDriver(scmHTTPClient, cfg.SCM.BaseURL, cfg.SCM.Token.Value())
+	case config.PlatformAzure:
+		scmDriver, err = scm.NewAzureDevOpsDriver(scmHTTPClient, cfg.SCM.BaseURL, cfg.SCM.Organization, cfg.SCM.Project, cfg.SCM.Token.Value())
+	case config.PlatformLocal:
+		local := cfg.SCM.Local
+		scmDriver, err = scm.NewLocalDriver(local.Dir, local.Base, local.Head, local.Staged, os.Stdout)
 	default:
 		return nil, fmt.Errorf("unsupported SCM platform: %s", cfg.SCM.Platform)
 	}
@@ -36,14 +60,7 @@ func Initialize(ctx context.Context, cfg *config.Config) (*reviewer.Engine, erro
 	scmClient := scm.NewClient(scmDriver, cfg.SCM)
 
 	// Initialize LLM Driver
-	var llmDriver llm.Driver
-	switch cfg.LLM.Provider {
-	case config.ProviderGemini:
-		llmDriver, err = llm.NewGeminiDriver(ctx, cfg.LLM.APIKey)
-	default:
-		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.LLM.Provider)
-	}
-
+	llmDriver, err := newLLMDriver(ctx, &httpClient, cfg.LLM)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize LLM driver: %w", err)
 	}
@@ -52,3 +69,84 @@ func Initialize(ctx context.Context, cfg *config.Config) (*reviewer.Engine, erro
 
 	return reviewer.NewEngine(*cfg, scmClient, llmClient), nil
 }
+
+// newGitHubDriver creates a GitHub driver authenticated as the configured
+// GitHub App, or with the token otherwise.
+func newGitHubDriver(httpClient *http.Client, cfg config.SCM, opts []scm.GitHubOption) (scm.Driver, error) {
+	if cfg.GitHubApp.ID == 0 {
+		return scm.NewGitHubDriver(httpClient, cfg.Token.Value(), opts...)
+	}
+
+	privateKey, err := os.ReadFile(cfg.GitHubApp.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read github app private key: %w", err)
+	}
+
+	return scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{
+		ID:             cfg.GitHubApp.ID,
+		InstallationID: cfg.GitHubApp.InstallationID,
+		PrivateKey:     privateKey,
+		Owner:          cfg.Owner,
+		Repo:           cfg.Repo,
+	}, opts...)
+}
+
+// newLLMDriver creates the driver of the configured provider, wrapped in a
+// fallback chain when fallback models are configured. Each request to a
+// provider is bounded by its request timeout.
+func newLLMDriver(ctx context.Context, httpClient *http.Client, cfg config.LLM) (llm.Driver, error) {
+	if len(cfg.Fallbacks) == 0 {
+		driver, err := newProviderDriver(ctx, httpClient, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewTimeoutDriver(driver, time.Duration(cfg.RequestTimeout)*time.Second), nil
+	}
+
+	fallbacks, err := cfg.FallbackConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []llm.FallbackEntry
+	for _, entryCfg := range append([]config.LLM{cfg}, fallbacks...) {
+		driver, err := newProviderDriver(ctx, httpClient, entryCfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", entryCfg.Provider, entryCfg.Model, err)
+		}
+
+		entries = append(entries, llm.FallbackEntry{
+			Name:   fmt.Sprintf("%s/%s", entryCfg.Provider, entryCfg.Model),
+			Model:  entryCfg.Model,
+			Driver: llm.NewTimeoutDriver(driver, time.Duration(entryCfg.RequestTimeout)*time.Second),
+		})
+	}
+
+	return llm.NewFallbackDriver(entries)
+}
+
+func newProviderDriver(ctx context.Context, httpClient *http.Client, cfg config.LLM) (llm.Driver, error) {
+	switch cfg.Provider {
+	case config.ProviderGemini:
+		return newGeminiDriver(ctx, cfg)
+	case config.ProviderOpenAI:
+		return llm.NewOpenAIDriver(httpClient, cfg.APIKey.Value(), cfg.BaseURL, cfg.Organization)
+	case config.ProviderAnthropic:
+		return llm.NewAnthropicDriver(httpClient, cfg.APIKey.Value(), cfg.BaseURL)
+	case config.ProviderOllama:
+		return llm.NewOllamaDriver(httpClient, cfg.BaseURL)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+}
+
+func newGeminiDriver(ctx context.Context, cfg config.LLM) (llm.Driver, error) {
+	switch cfg.Backend {
+	case config.GeminiBackendAPI, "":
+		return llm.NewGeminiDriver(ctx, cfg.APIKey.Value())
+	case config.GeminiBackendVertexAI:
+		return llm.NewVertexAIGeminiDriver(ctx, cfg.Project, cfg.Location, cfg.CredentialsFile)
+	default:
+		return nil, fmt.Errorf("unsupported Gemini backend: %s", cfg.Backend)
+	}
+}
@@ -2,11 +2,19 @@ package bootstrap_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/fzl-22/elgtm/internal/bootstrap"
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBootstrap_Initialize(t *testing.T) {
@@ -31,6 +39,262 @@ func TestBootstrap_Initialize(t *testing.T) {
 		assert.NotNil(t, engine)
 	})
 
+	t.Run("Success_InitializeOpenAIEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOpenAI,
+				BaseURL:  "http://localhost:8000/v1",
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeAnthropicEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderAnthropic,
+				APIKey:   "fake-api-key",
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeOllamaEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeLocalEngine", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+
+		// The engine is initialized on a repository of its own, since the
+		// package may not be built from a git checkout.
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Project\n"), 0644))
+		for _, args := range [][]string{
+			{"init", "-q"},
+			{"config", "user.name", "Jane Doe"},
+			{"config", "user.email", "jane@example.com"},
+			{"add", "."},
+			{"commit", "-q", "-m", "Initial commit"},
+		} {
+			cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+			cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null")
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformLocal,
+				Local:    config.Local{Dir: dir, Staged: true},
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeGitHubEnterpriseEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform:           config.PlatformGitHub,
+				Token:              "fake-token",
+				BaseURL:            "https://github.example.com/api/v3/",
+				UploadURL:          "https://github.example.com/api/uploads/",
+				InsecureSkipVerify: true,
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeSelfManagedGitLabEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitLab,
+				Token:    "fake-token",
+				BaseURL:  "https://gitlab.example.com",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeBitbucketEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformBitbucket,
+				Token:    "fake-app-password",
+				Username: "reviewer",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeGiteaEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitea,
+				Token:    "fake-token",
+				BaseURL:  "https://forgejo.example.com",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeAzureDevOpsEngine", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform:     config.PlatformAzure,
+				Token:        "fake-pat",
+				Organization: "contoso",
+				Project:      "Fabrikam",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Success_InitializeGitHubAppEngine", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		keyFile := filepath.Join(t.TempDir(), "app.pem")
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform:  config.PlatformGitHub,
+				Owner:     "owner",
+				Repo:      "repo",
+				GitHubApp: config.GitHubApp{ID: 123, PrivateKeyFile: keyFile},
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Failure_MissingGitHubAppPrivateKey", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform:  config.PlatformGitHub,
+				GitHubApp: config.GitHubApp{ID: 123, PrivateKeyFile: "/nonexistent/app.pem"},
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.ErrorContains(t, err, "failed to initialize SCM driver: failed to read github app private key")
+		assert.Nil(t, engine)
+	})
+
+	t.Run("Failure_InvalidCACertFile", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform:   config.PlatformGitHub,
+				Token:      "fake-token",
+				CACertFile: "/nonexistent/ca.pem",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderOllama,
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.ErrorContains(t, err, "failed to initialize SCM driver: failed to read CA certificates")
+		assert.Nil(t, engine)
+	})
+
 	t.Run("Failure_UnsupportedSCMPlatform", func(t *testing.T) {
 		cfg := &config.Config{
 			System: config.System{Timeout: 30},
@@ -112,4 +376,111 @@ func TestBootstrap_Initialize(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to initialize LLM driver")
 		assert.Nil(t, engine)
 	})
+
+	t.Run("Failure_VertexAIWithoutProject", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderGemini,
+				Backend:  config.GeminiBackendVertexAI,
+				Location: "us-central1",
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the Vertex AI backend requires a project")
+		assert.Nil(t, engine)
+	})
+
+	t.Run("Failure_UnsupportedGeminiBackend", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider: config.ProviderGemini,
+				Backend:  "anything-backend",
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported Gemini backend: anything-backend")
+		assert.Nil(t, engine)
+	})
+
+	t.Run("Success_InitializeFallbackChain", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider:  config.ProviderGemini,
+				Model:     "gemini-2.5-pro",
+				APIKey:    "fake-api-key",
+				Fallbacks: []string{"gemini/gemini-2.5-flash", "ollama/qwen2.5-coder"},
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, engine)
+	})
+
+	t.Run("Failure_FallbackDriverInitError", func(t *testing.T) {
+		t.Setenv("ANTHROPIC_API_KEY", "")
+
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider:  config.ProviderGemini,
+				Model:     "gemini-2.5-pro",
+				APIKey:    "fake-api-key",
+				Fallbacks: []string{"anthropic/claude-sonnet-4-5"},
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "anthropic/claude-sonnet-4-5: api key is required for the Anthropic API")
+		assert.Nil(t, engine)
+	})
+
+	t.Run("Failure_InvalidFallback", func(t *testing.T) {
+		cfg := &config.Config{
+			System: config.System{Timeout: 30},
+			SCM: config.SCM{
+				Platform: config.PlatformGitHub,
+				Token:    "fake-token",
+			},
+			LLM: config.LLM{
+				Provider:  config.ProviderGemini,
+				APIKey:    "fake-api-key",
+				Fallbacks: []string{"gpt-4o"},
+			},
+		}
+
+		engine, err := bootstrap.Initialize(ctx, cfg)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid LLM fallback")
+		assert.Nil(t, engine)
+	})
 }
@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -13,39 +16,171 @@ type Config struct {
 	LLM    LLM    `mapstructure:"llm"`
 	Review Review `mapstructure:"review"`
 	System System `mapstructure:"system"`
+
+	sources []Source
+}
+
+// Sources returns where the value of every key came from, in the order of
+// the Config struct.
+func (c *Config) Sources() []Source {
+	return c.sources
 }
 
 type SCMPlatform string
 
 const (
-	PlatformGitHub SCMPlatform = "github"
-	PlatformGitLab SCMPlatform = "gitlab"
+	PlatformGitHub    SCMPlatform = "github"
+	PlatformGitLab    SCMPlatform = "gitlab"
+	PlatformBitbucket SCMPlatform = "bitbucket"
+	PlatformGitea     SCMPlatform = "gitea"
+	PlatformAzure     SCMPlatform = "azure"
+	PlatformLocal     SCMPlatform = "local"
 )
 
 type SCM struct {
-	Platform    SCMPlatform `mapstructure:"platform"`
-	Token       string      `mapstructure:"token"`
-	Owner       string      `mapstructure:"owner"`
-	Repo        string      `mapstructure:"repo"`
-	PRNumber    int         `mapstructure:"pr_number"`
-	MaxDiffSize int64       `mapstructure:"max_diff_size"`
+	Platform           SCMPlatform `mapstructure:"platform"`
+	Token              Secret      `mapstructure:"token"`
+	Username           string      `mapstructure:"username"`
+	Owner              string      `mapstructure:"owner"`
+	Repo               string      `mapstructure:"repo"`
+	Organization       string      `mapstructure:"organization"`
+	Project            string      `mapstructure:"project"`
+	PRNumber           int         `mapstructure:"pr_number"`
+	MaxDiffSize        int64       `mapstructure:"max_diff_size"`
+	BaseURL            string      `mapstructure:"base_url"`
+	UploadURL          string      `mapstructure:"upload_url"`
+	CACertFile         string      `mapstructure:"ca_cert_file"`
+	InsecureSkipVerify bool        `mapstructure:"insecure_skip_verify"`
+	GitHubApp          GitHubApp   `mapstructure:"github_app"`
+	Retry              Retry       `mapstructure:"retry"`
+	Local              Local       `mapstructure:"local"`
+}
+
+// GitHubApp authenticates the github platform as an installation of a GitHub
+// App instead of with a token.
+type GitHubApp struct {
+	ID             int64  `mapstructure:"id"`
+	InstallationID int64  `mapstructure:"installation_id"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+}
+
+type Local struct {
+	Dir    string `mapstructure:"dir"`
+	Base   string `mapstructure:"base"`
+	Head   string `mapstructure:"head"`
+	Staged bool   `mapstructure:"staged"`
 }
 
 type LLMProvider string
 
-const ProviderGemini LLMProvider = "gemini"
+const (
+	ProviderGemini    LLMProvider = "gemini"
+	ProviderOpenAI    LLMProvider = "openai"
+	ProviderAnthropic LLMProvider = "anthropic"
+	ProviderOllama    LLMProvider = "ollama"
+)
+
+type GeminiBackend string
+
+const (
+	GeminiBackendAPI      GeminiBackend = "gemini_api"
+	GeminiBackendVertexAI GeminiBackend = "vertex_ai"
+)
 
 type LLM struct {
-	Provider    LLMProvider `mapstructure:"provider"`
-	Model       string      `mapstructure:"model"`
-	APIKey      string      `mapstructure:"api_key"`
-	Temperature float32     `mapstructure:"temperature"`
-	MaxTokens   int         `mapstructure:"max_tokens"`
+	Provider        LLMProvider   `mapstructure:"provider"`
+	Model           string        `mapstructure:"model"`
+	APIKey          Secret        `mapstructure:"api_key"`
+	BaseURL         string        `mapstructure:"base_url"`
+	Organization    string        `mapstructure:"organization"`
+	Backend         GeminiBackend `mapstructure:"backend"`
+	Project         string        `mapstructure:"project"`
+	Location        string        `mapstructure:"location"`
+	CredentialsFile string        `mapstructure:"credentials_file"`
+	SystemPrompt    string        `mapstructure:"system_prompt"`
+	Temperature     float32       `mapstructure:"temperature"`
+	MaxTokens       int           `mapstructure:"max_tokens"`
+	ContextWindow   int           `mapstructure:"context_window"`
+	Concurrency     int           `mapstructure:"concurrency"`
+	RequestTimeout  int           `mapstructure:"request_timeout"`
+	Fallbacks       []string      `mapstructure:"fallbacks"`
+	Retry           Retry         `mapstructure:"retry"`
+}
+
+// FallbackConfigs returns the settings of each fallback entry, given as
+// "provider/model". An entry for the same provider inherits every setting but
+// the context window, which is looked up from its model. An entry for another
+// provider only inherits the settings that do not depend on the provider and
+// takes its API key from the provider's conventional environment variable,
+// such as ANTHROPIC_API_KEY, or from the file named by ANTHROPIC_API_KEY_FILE.
+func (l LLM) FallbackConfigs() ([]LLM, error) {
+	configs := make([]LLM, 0, len(l.Fallbacks))
+
+	for _, entry := range l.Fallbacks {
+		provider, model, ok := strings.Cut(strings.TrimSpace(entry), "/")
+		if !ok || provider == "" || model == "" {
+			return nil, fmt.Errorf("invalid LLM fallback %q: expected provider/model", entry)
+		}
+
+		fallback := l
+		fallback.Provider = LLMProvider(provider)
+		fallback.Model = model
+		fallback.ContextWindow = 0
+		fallback.Fallbacks = nil
+
+		if fallback.Provider != l.Provider {
+			apiKey, err := secretFromEnv(strings.ToUpper(provider) + "_API_KEY")
+			if err != nil {
+				return nil, err
+			}
+			fallback.APIKey = apiKey
+			fallback.BaseURL = ""
+			fallback.Organization = ""
+			fallback.Backend = ""
+			fallback.Project = ""
+			fallback.Location = ""
+			fallback.CredentialsFile = ""
+		}
+
+		configs = append(configs, fallback)
+	}
+
+	return configs, nil
+}
+
+type Retry struct {
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BaseDelay   time.Duration `mapstructure:"base_delay"`
+	MaxDelay    time.Duration `mapstructure:"max_delay"`
+	Jitter      float64       `mapstructure:"jitter"`
 }
 
+type OutputMode string
+
+const (
+	OutputModeText       OutputMode = "text"
+	OutputModeStructured OutputMode = "structured"
+)
+
+type CommentStrategy string
+
+const (
+	CommentStrategyUpdate  CommentStrategy = "update"
+	CommentStrategyAppend  CommentStrategy = "append"
+	CommentStrategyReplace CommentStrategy = "replace"
+)
+
 type Review struct {
-	PromptType string `mapstructure:"prompt_type"`
-	PromptDir  string `mapstructure:"prompt_dir"`
+	PromptType      string          `mapstructure:"prompt_type"`
+	PromptDir       string          `mapstructure:"prompt_dir"`
+	OutputMode      OutputMode      `mapstructure:"output_mode"`
+	CommentStrategy CommentStrategy `mapstructure:"comment_strategy"`
+	Incremental     bool            `mapstructure:"incremental"`
+	DryRun          bool            `mapstructure:"dry_run"`
+	RenderOnly      bool            `mapstructure:"render_only"`
+	Output          string          `mapstructure:"output"`
+	Rules           []string        `mapstructure:"rules"`
+	Ignore          []string        `mapstructure:"ignore"`
 }
 
 type System struct {
@@ -53,34 +188,99 @@ type System struct {
 	Timeout  int    `mapstructure:"timeout"`
 }
 
-func NewConfig() (*Config, error) {
+// NewConfig loads the configuration in layers, each overriding the previous
+// one: the defaults, the system configuration file, the repository
+// configuration file, the environment and, when flags is not nil, the
+// command-line flags registered by BindFlags.
+func NewConfig(flags *pflag.FlagSet) (*Config, error) {
 	v := viper.New()
 
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	v.SetDefault("scm.max_diff_size", 2097152)
+	setDefaults(v)
 
-	v.SetDefault("llm.temperature", 0.2)
-	v.SetDefault("llm.max_tokens", 4096)
-
-	v.SetDefault("review.prompt_type", "general")
-	v.SetDefault("review.prompt_dir", ".reviewer")
-
-	v.SetDefault("system.log_level", "info")
-	v.SetDefault("system.timeout", 300)
+	files, err := readConfigFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := v.MergeConfigMap(file.settings.AllSettings()); err != nil {
+			return nil, fmt.Errorf("failed to merge config file [%s]: %w", file.path, err)
+		}
+	}
 
 	cfg := &Config{}
 
 	BindEnvs(v, cfg)
 
+	if flags != nil {
+		for _, key := range Keys() {
+			if flag := flags.Lookup(key.Flag); flag != nil {
+				if err := v.BindPFlag(key.Name, flag); err != nil {
+					return nil, fmt.Errorf("unable to bind flag --%s: %w", key.Flag, err)
+				}
+			}
+		}
+	}
+
+	// Secrets may be read from the file named by their environment variable
+	// with a _FILE suffix, which stands in for the variable.
+	for _, key := range Keys() {
+		if !key.secret() || os.Getenv(key.Env+"_FILE") == "" || (flags != nil && flags.Changed(key.Flag)) {
+			continue
+		}
+
+		secret, err := secretFromEnv(key.Env)
+		if err != nil {
+			return nil, err
+		}
+		v.Set(key.Name, secret.Value())
+	}
+
 	if err := v.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("unable to decode into struct: %w", err)
 	}
 
+	cfg.sources = resolveSources(flags, files)
+
 	return cfg, nil
 }
 
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("scm.max_diff_size", 2097152)
+	v.SetDefault("scm.insecure_skip_verify", false)
+	v.SetDefault("scm.retry.max_attempts", 3)
+	v.SetDefault("scm.retry.base_delay", "1s")
+	v.SetDefault("scm.retry.max_delay", "60s")
+	v.SetDefault("scm.retry.jitter", 0.2)
+	v.SetDefault("scm.local.dir", ".")
+	v.SetDefault("scm.local.base", "main")
+	v.SetDefault("scm.local.head", "HEAD")
+	v.SetDefault("scm.local.staged", false)
+
+	v.SetDefault("llm.backend", "gemini_api")
+	v.SetDefault("llm.temperature", 0.2)
+	v.SetDefault("llm.max_tokens", 4096)
+	v.SetDefault("llm.concurrency", 4)
+	v.SetDefault("llm.request_timeout", 120)
+	v.SetDefault("llm.retry.max_attempts", 3)
+	v.SetDefault("llm.retry.base_delay", "2s")
+	v.SetDefault("llm.retry.max_delay", "60s")
+	v.SetDefault("llm.retry.jitter", 0.2)
+
+	v.SetDefault("review.prompt_type", "general")
+	v.SetDefault("review.prompt_dir", ".reviewer")
+	v.SetDefault("review.output_mode", "text")
+	v.SetDefault("review.comment_strategy", "update")
+	v.SetDefault("review.incremental", true)
+	v.SetDefault("review.dry_run", false)
+	v.SetDefault("review.render_only", false)
+
+	v.SetDefault("system.log_level", "info")
+	v.SetDefault("system.timeout", 300)
+}
+
 func BindEnvs(v *viper.Viper, iface any, parts ...string) {
 	ifv := reflect.ValueOf(iface)
 	ift := reflect.TypeOf(iface)
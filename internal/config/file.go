@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultSystemConfigFile is the configuration file shared by every
+	// repository on the machine, or in the container image.
+	DefaultSystemConfigFile = "/etc/elgtm/config.yaml"
+	// DefaultRepoConfigFile is the configuration file committed to the
+	// repository under review, relative to the working directory.
+	DefaultRepoConfigFile = ".elgtm.yaml"
+)
+
+// repoConfigKeys are the keys the repository configuration file may set. The
+// file comes from the pull request under review, so it is limited to how the
+// review is done and which model does it: endpoints, TLS, credentials, the
+// system prompt and output paths would let the author of the pull request
+// redirect the tokens, steer the model or write files. Other keys are reported
+// as errors rather than ignored.
+var repoConfigKeys = []string{
+	"llm.model",
+	"llm.temperature",
+	"llm.max_tokens",
+	"review.prompt_type",
+	"review.output_mode",
+	"review.comment_strategy",
+	"review.incremental",
+	"review.rules",
+	"review.ignore",
+}
+
+// Source tells which layer of the configuration a key's value comes from: a
+// flag, an environment variable, a configuration file or the default.
+type Source struct {
+	Key    string
+	Origin string
+}
+
+// configFile is a configuration file and the settings read from it.
+type configFile struct {
+	path     string
+	settings *viper.Viper
+}
+
+// readConfigFiles reads the system configuration file, named by
+// ELGTM_SYSTEM_CONFIG or DefaultSystemConfigFile, and the repository
+// configuration file, named by ELGTM_CONFIG or DefaultRepoConfigFile, in order
+// of precedence. The default files are optional, files named explicitly are
+// not. The repository file may only set repoConfigKeys.
+func readConfigFiles() ([]configFile, error) {
+	var files []configFile
+
+	for _, location := range []struct {
+		env, path  string
+		repository bool
+	}{
+		{"ELGTM_SYSTEM_CONFIG", DefaultSystemConfigFile, false},
+		{"ELGTM_CONFIG", DefaultRepoConfigFile, true},
+	} {
+		path, explicit := os.LookupEnv(location.env)
+		if !explicit || path == "" {
+			path, explicit = location.path, false
+		}
+
+		file, err := readConfigFile(path, location.repository)
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+func readConfigFile(path string, repository bool) (configFile, error) {
+	if _, err := os.Stat(path); err != nil {
+		return configFile{}, fmt.Errorf("failed to read config file [%s]: %w", path, err)
+	}
+
+	settings := viper.New()
+	settings.SetConfigFile(path)
+	settings.SetConfigType("yaml")
+	if err := settings.ReadInConfig(); err != nil {
+		return configFile{}, fmt.Errorf("failed to read config file [%s]: %w", path, err)
+	}
+
+	known := make([]string, 0, len(Keys()))
+	for _, key := range Keys() {
+		known = append(known, key.Name)
+	}
+	for _, key := range settings.AllKeys() {
+		if !slices.Contains(known, key) {
+			return configFile{}, fmt.Errorf("unknown key %q in config file [%s]", key, path)
+		}
+		if repository && !slices.Contains(repoConfigKeys, key) {
+			return configFile{}, fmt.Errorf("key %q cannot be set in the repository config file [%s], only in the system config file, environment variables or flags", key, path)
+		}
+	}
+
+	return configFile{path: path, settings: settings}, nil
+}
+
+// resolveSources returns the origin of the value of every key, following the
+// precedence of NewConfig: flags, then environment variables, then the
+// configuration files, then the defaults.
+func resolveSources(flags *pflag.FlagSet, files []configFile) []Source {
+	var sources []Source
+
+	for _, key := range Keys() {
+		origin := "default"
+		if key.Default == "" {
+			origin = "unset"
+		}
+
+		for _, file := range files {
+			if file.settings.IsSet(key.Name) {
+				origin = "file " + file.path
+			}
+		}
+
+		if value, ok := os.LookupEnv(key.Env); ok && value != "" {
+			origin = "env " + key.Env
+		}
+		if key.secret() && os.Getenv(key.Env+"_FILE") != "" {
+			origin = "env " + key.Env + "_FILE"
+		}
+
+		if flags != nil && flags.Changed(key.Flag) {
+			origin = "flag --" + key.Flag
+		}
+
+		sources = append(sources, Source{Key: key.Name, Origin: origin})
+	}
+
+	return sources
+}
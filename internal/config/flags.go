@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Key is a configuration key along with the environment variable and the
+// command-line flag that set it.
+type Key struct {
+	// Name is the dotted path of the key, e.g. "llm.model".
+	Name    string
+	Env     string
+	Flag    string
+	Default string
+	Usage   string
+
+	typ reflect.Type
+}
+
+var usages = map[string]string{
+	"scm.platform":                    "Source control platform: github, gitlab, bitbucket, gitea, azure, local",
+	"scm.token":                       "Access token of the SCM platform, or read from SCM_TOKEN_FILE",
+	"scm.username":                    "Bitbucket user the token is an app password of, unset for an access token",
+	"scm.owner":                       "Owner of the repository",
+	"scm.repo":                        "Name of the repository",
+	"scm.organization":                "Azure DevOps organization of the project",
+	"scm.project":                     "Azure DevOps project of the repository",
+	"scm.pr_number":                   "Number of the pull request to review",
+	"scm.max_diff_size":               "Max characters of diff to process",
+	"scm.base_url":                    "API URL of GitHub Enterprise Server, or URL of another self-hosted instance",
+	"scm.upload_url":                  "Upload URL of GitHub Enterprise Server, the base URL when unset",
+	"scm.ca_cert_file":                "PEM file of the CA certificates of a self-hosted instance",
+	"scm.insecure_skip_verify":        "Skip the verification of the certificate of a self-hosted instance",
+	"scm.github_app.id":               "ID of the GitHub App to authenticate as instead of the token",
+	"scm.github_app.installation_id":  "Installation of the GitHub App, looked up from the repository when unset",
+	"scm.github_app.private_key_file": "PEM file of the private key of the GitHub App",
+	"scm.retry.max_attempts":          "Attempts per SCM request on rate limits, 5xx or network errors",
+	"scm.retry.base_delay":            "First SCM retry delay, doubled on each retry",
+	"scm.retry.max_delay":             "Longest SCM retry delay, or rate limit wait, to accept",
+	"scm.retry.jitter":                "Random fraction shaved off each SCM retry delay",
+	"scm.local.dir":                   "Repository to review with the local platform",
+	"scm.local.base":                  "Ref the local changes are compared against",
+	"scm.local.head":                  "Ref holding the local changes",
+	"scm.local.staged":                "Review the staged changes instead of base...head",
+
+	"llm.provider":           "LLM provider: gemini, openai, anthropic, ollama",
+	"llm.model":              "Model ID, e.g. gemini-2.5-flash",
+	"llm.api_key":            "API key of the LLM provider, or read from LLM_API_KEY_FILE",
+	"llm.base_url":           "Base URL of the provider API, or the Ollama host",
+	"llm.organization":       "OpenAI organization ID",
+	"llm.backend":            "Gemini backend: gemini_api, vertex_ai",
+	"llm.project":            "Google Cloud project ID (vertex_ai only)",
+	"llm.location":           "Google Cloud location (vertex_ai only)",
+	"llm.credentials_file":   "Service account key file (vertex_ai only)",
+	"llm.system_prompt":      "System prompt sent with every request",
+	"llm.temperature":        "Sampling temperature (0.0 - 2.0, up to 1.0 for anthropic)",
+	"llm.max_tokens":         "Max output tokens of the review",
+	"llm.context_window":     "Model context window in tokens, looked up from the model when unset",
+	"llm.concurrency":        "Max chunks reviewed in parallel",
+	"llm.request_timeout":    "Timeout of a single LLM request in seconds",
+	"llm.fallbacks":          "provider/model list tried in order on 429 or 5xx errors",
+	"llm.retry.max_attempts": "Attempts per LLM request on 429, 5xx or network errors",
+	"llm.retry.base_delay":   "First LLM retry delay, doubled on each retry",
+	"llm.retry.max_delay":    "Longest LLM retry delay, or Retry-After wait, to accept",
+	"llm.retry.jitter":       "Random fraction shaved off each LLM retry delay",
+
+	"review.prompt_type":      "Prompt filename in the prompt directory, without extension",
+	"review.prompt_dir":       "Prompt directory",
+	"review.output_mode":      "Review format: text, structured",
+	"review.comment_strategy": "Previous summary comment handling: update, append, replace",
+	"review.incremental":      "Review only the commits pushed since the last review",
+	"review.dry_run":          "Write the review to the output instead of posting it",
+	"review.render_only":      "Write the rendered prompt without calling the LLM",
+	"review.output":           "File to write dry run output to, stdout when unset",
+	"review.rules":            "Team rules added to the prompt of every review",
+	"review.ignore":           "Glob patterns of files left out of the review",
+
+	"system.log_level": "Log level: debug, info, warn, error",
+	"system.timeout":   "Timeout of the whole run in seconds",
+}
+
+// flagAliases are short flag names accepted for the most common keys.
+var flagAliases = map[string]string{
+	"dry-run":     "review-dry-run",
+	"render-only": "review-render-only",
+	"output":      "review-output",
+}
+
+// Keys returns every configuration key, in the order of the Config struct.
+func Keys() []Key {
+	v := viper.New()
+	setDefaults(v)
+
+	var keys []Key
+	walkKeys(reflect.TypeFor[Config](), nil, func(name string, typ reflect.Type) {
+		key := Key{
+			Name:  name,
+			Env:   envName(name),
+			Flag:  flagName(name),
+			Usage: usages[name],
+			typ:   typ,
+		}
+		if v.IsSet(name) {
+			key.Default = fmt.Sprint(v.Get(name))
+		}
+		keys = append(keys, key)
+	})
+
+	return keys
+}
+
+// secret reports whether the key holds a Secret, which may also be read from
+// the file named by its environment variable with a _FILE suffix.
+func (k Key) secret() bool {
+	return k.typ == reflect.TypeFor[Secret]()
+}
+
+// envName returns the environment variable of a key, e.g. LLM_MODEL.
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// flagName returns the flag of a key, without the leading dashes, e.g.
+// llm-model.
+func flagName(name string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(name)
+}
+
+func walkKeys(typ reflect.Type, parts []string, fn func(name string, typ reflect.Type)) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tv, ok := field.Tag.Lookup("mapstructure")
+		if !ok {
+			continue
+		}
+
+		path := append(parts[:len(parts):len(parts)], tv)
+		if field.Type.Kind() == reflect.Struct {
+			walkKeys(field.Type, path, fn)
+			continue
+		}
+		fn(strings.Join(path, "."), field.Type)
+	}
+}
+
+// BindFlags registers a flag for every configuration key, to be passed to
+// NewConfig once parsed. The flags have no defaults of their own: a flag that
+// is not set leaves the key to its environment variable or default.
+func BindFlags(flags *pflag.FlagSet) {
+	for _, key := range Keys() {
+		switch {
+		case key.typ == reflect.TypeFor[time.Duration]():
+			flags.Duration(key.Flag, 0, key.Usage)
+		case key.typ.Kind() == reflect.Bool:
+			flags.Bool(key.Flag, false, key.Usage)
+		case key.typ.Kind() == reflect.Int, key.typ.Kind() == reflect.Int64:
+			flags.Int64(key.Flag, 0, key.Usage)
+		case key.typ.Kind() == reflect.Float32, key.typ.Kind() == reflect.Float64:
+			flags.Float64(key.Flag, 0, key.Usage)
+		case key.typ.Kind() == reflect.Slice:
+			flags.StringSlice(key.Flag, nil, key.Usage)
+		default:
+			flags.String(key.Flag, "", key.Usage)
+		}
+	}
+
+	flags.SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if alias, ok := flagAliases[name]; ok {
+			name = alias
+		}
+		return pflag.NormalizedName(name)
+	})
+}
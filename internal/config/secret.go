@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a configuration value, such as a token or an API key, that is
+// redacted when printed, logged or marshaled. Value returns the secret itself.
+type Secret string
+
+// Value returns the secret in the clear, to be handed to the client that
+// needs it.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns a redacted form of the secret, empty when the secret is.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// secretFromEnv returns the secret held by the environment variable env or,
+// for Docker and Kubernetes secrets, in the file named by env + "_FILE".
+// Setting both is an error.
+func secretFromEnv(env string) (Secret, error) {
+	value := os.Getenv(env)
+
+	path := os.Getenv(env + "_FILE")
+	if path == "" {
+		return Secret(value), nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("%s and %s_FILE cannot both be set", env, env)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", env, err)
+	}
+
+	return Secret(strings.TrimSpace(string(content))), nil
+}
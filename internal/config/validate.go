@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks the settings required by the SCM platform and the LLM
+// provider, the ranges of numeric settings and the options that cannot be
+// combined. It returns a *ValidationError listing every problem found, naming
+// the environment variable and the flag that fix each one.
+func (c *Config) Validate() error {
+	var p problems
+
+	c.validateSCM(&p)
+	c.validateLLM(&p)
+	c.validateReview(&p)
+
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, c.System.LogLevel) {
+		p.add("%s must be one of debug, info, warn, error, got %q", setting("system.log_level"), c.System.LogLevel)
+	}
+	if c.System.Timeout <= 0 {
+		p.add("%s must be greater than 0, got %d", setting("system.timeout"), c.System.Timeout)
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+func (c *Config) validateSCM(p *problems) {
+	scm := c.SCM
+
+	switch scm.Platform {
+	case PlatformGitHub, PlatformGitLab, PlatformBitbucket, PlatformGitea:
+		if scm.GitHubApp.ID == 0 {
+			p.required("scm.token", scm.Token.Value())
+		}
+		p.required("scm.owner", scm.Owner)
+		p.required("scm.repo", scm.Repo)
+		if scm.PRNumber <= 0 {
+			p.add("%s must be greater than 0, got %d", setting("scm.pr_number"), scm.PRNumber)
+		}
+	case PlatformAzure:
+		p.required("scm.token", scm.Token.Value())
+		p.required("scm.organization", scm.Organization)
+		p.required("scm.project", scm.Project)
+		p.required("scm.repo", scm.Repo)
+		if scm.PRNumber <= 0 {
+			p.add("%s must be greater than 0, got %d", setting("scm.pr_number"), scm.PRNumber)
+		}
+	case PlatformLocal:
+		p.required("scm.local.dir", scm.Local.Dir)
+		if scm.Local.Staged {
+			for _, name := range []string{"scm.local.base", "scm.local.head"} {
+				if c.isSet(name) {
+					p.add("%s cannot be combined with %s", setting("scm.local.staged"), setting(name))
+				}
+			}
+		} else {
+			p.required("scm.local.base", scm.Local.Base)
+			p.required("scm.local.head", scm.Local.Head)
+		}
+	case "":
+		p.add("%s is required", setting("scm.platform"))
+	default:
+		p.add("%s must be one of github, gitlab, bitbucket, gitea, azure, local, got %q", setting("scm.platform"), scm.Platform)
+	}
+
+	if scm.BaseURL != "" {
+		p.url("scm.base_url", scm.BaseURL)
+	} else if scm.Platform == PlatformGitea {
+		p.add("%s is required with the gitea platform", setting("scm.base_url"))
+	}
+	if scm.UploadURL != "" {
+		p.url("scm.upload_url", scm.UploadURL)
+		if scm.Platform != PlatformGitHub || scm.BaseURL == "" {
+			p.add("%s requires %s with the github platform", setting("scm.upload_url"), setting("scm.base_url"))
+		}
+	}
+	if scm.Username != "" && scm.Platform != PlatformBitbucket {
+		p.add("%s requires the bitbucket platform", setting("scm.username"))
+	}
+	if scm.Platform != PlatformAzure {
+		for _, name := range []string{"scm.organization", "scm.project"} {
+			if c.isSet(name) {
+				p.add("%s requires the azure platform", setting(name))
+			}
+		}
+	}
+	if scm.CACertFile != "" && scm.InsecureSkipVerify {
+		p.add("%s cannot be combined with %s", setting("scm.ca_cert_file"), setting("scm.insecure_skip_verify"))
+	}
+
+	c.validateGitHubApp(p)
+
+	if scm.MaxDiffSize <= 0 {
+		p.add("%s must be greater than 0, got %d", setting("scm.max_diff_size"), scm.MaxDiffSize)
+	}
+
+	validateRetry(p, "scm.retry", scm.Retry)
+}
+
+func (c *Config) validateGitHubApp(p *problems) {
+	app := c.SCM.GitHubApp
+
+	if app.ID == 0 {
+		if app.InstallationID != 0 || app.PrivateKeyFile != "" {
+			p.add("%s is required with the other GitHub App settings", setting("scm.github_app.id"))
+		}
+		return
+	}
+
+	if app.ID < 0 {
+		p.add("%s must be greater than 0, got %d", setting("scm.github_app.id"), app.ID)
+	}
+	if app.InstallationID < 0 {
+		p.add("%s cannot be negative, got %d", setting("scm.github_app.installation_id"), app.InstallationID)
+	}
+	if c.SCM.Platform != PlatformGitHub {
+		p.add("%s requires the github platform", setting("scm.github_app.id"))
+	}
+	if c.SCM.Token != "" {
+		p.add("%s cannot be combined with %s", setting("scm.github_app.id"), setting("scm.token"))
+	}
+	p.required("scm.github_app.private_key_file", app.PrivateKeyFile)
+}
+
+func (c *Config) validateLLM(p *problems) {
+	l := c.LLM
+
+	validateProvider(p, "", l, setting("llm.api_key"))
+
+	if l.MaxTokens <= 0 {
+		p.add("%s must be greater than 0, got %d", setting("llm.max_tokens"), l.MaxTokens)
+	}
+	if l.ContextWindow < 0 {
+		p.add("%s cannot be negative, got %d", setting("llm.context_window"), l.ContextWindow)
+	}
+	if l.Concurrency < 1 {
+		p.add("%s must be at least 1, got %d", setting("llm.concurrency"), l.Concurrency)
+	}
+	if l.RequestTimeout < 0 {
+		p.add("%s cannot be negative, got %d", setting("llm.request_timeout"), l.RequestTimeout)
+	}
+
+	fallbacks, err := l.FallbackConfigs()
+	if err != nil {
+		p.add("%s: %v", setting("llm.fallbacks"), err)
+	}
+	for _, fallback := range fallbacks {
+		prefix := fmt.Sprintf("fallback %s/%s: ", fallback.Provider, fallback.Model)
+		apiKey := setting("llm.api_key")
+		if fallback.Provider != l.Provider {
+			apiKey = strings.ToUpper(string(fallback.Provider)) + "_API_KEY"
+		}
+		validateProvider(p, prefix, fallback, apiKey)
+	}
+
+	validateRetry(p, "llm.retry", l.Retry)
+}
+
+// validateProvider checks the settings that depend on the provider, for the
+// main model and for each fallback model, whose problems are prefixed with the
+// entry. apiKey names where the API key of the provider comes from.
+func validateProvider(p *problems, prefix string, l LLM, apiKey string) {
+	if l.Model == "" {
+		p.add("%s%s is required", prefix, setting("llm.model"))
+	}
+
+	maxTemperature := float32(2)
+
+	switch l.Provider {
+	case ProviderGemini:
+		switch l.Backend {
+		case GeminiBackendAPI, "":
+			if l.APIKey == "" {
+				p.add("%s%s is required with the gemini_api backend", prefix, apiKey)
+			}
+		case GeminiBackendVertexAI:
+			if l.Project == "" {
+				p.add("%s%s is required with the vertex_ai backend", prefix, setting("llm.project"))
+			}
+			if l.Location == "" {
+				p.add("%s%s is required with the vertex_ai backend", prefix, setting("llm.location"))
+			}
+		default:
+			p.add("%s%s must be one of gemini_api, vertex_ai, got %q", prefix, setting("llm.backend"), l.Backend)
+		}
+	case ProviderOpenAI:
+		if l.APIKey == "" && l.BaseURL == "" {
+			p.add("%s%s is required unless %s points to a compatible server", prefix, apiKey, setting("llm.base_url"))
+		}
+	case ProviderAnthropic:
+		if l.APIKey == "" {
+			p.add("%s%s is required", prefix, apiKey)
+		}
+		maxTemperature = 1
+	case ProviderOllama:
+	case "":
+		p.add("%s%s is required", prefix, setting("llm.provider"))
+	default:
+		p.add("%s%s must be one of gemini, openai, anthropic, ollama, got %q", prefix, setting("llm.provider"), l.Provider)
+	}
+
+	if l.Temperature < 0 || l.Temperature > maxTemperature {
+		p.add("%s%s must be between 0 and %g, got %g", prefix, setting("llm.temperature"), maxTemperature, l.Temperature)
+	}
+}
+
+func validateRetry(p *problems, prefix string, r Retry) {
+	if r.MaxAttempts < 1 {
+		p.add("%s must be at least 1, got %d", setting(prefix+".max_attempts"), r.MaxAttempts)
+	}
+	if r.BaseDelay < 0 {
+		p.add("%s cannot be negative, got %s", setting(prefix+".base_delay"), r.BaseDelay)
+	}
+	if r.MaxDelay < r.BaseDelay {
+		p.add("%s must be at least %s, got %s", setting(prefix+".max_delay"), setting(prefix+".base_delay"), r.MaxDelay)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		p.add("%s must be between 0 and 1, got %g", setting(prefix+".jitter"), r.Jitter)
+	}
+}
+
+func (c *Config) validateReview(p *problems) {
+	r := c.Review
+
+	p.required("review.prompt_type", r.PromptType)
+
+	if !slices.Contains([]OutputMode{OutputModeText, OutputModeStructured}, r.OutputMode) {
+		p.add("%s must be one of text, structured, got %q", setting("review.output_mode"), r.OutputMode)
+	}
+
+	strategies := []CommentStrategy{CommentStrategyUpdate, CommentStrategyAppend, CommentStrategyReplace}
+	if !slices.Contains(strategies, r.CommentStrategy) {
+		p.add("%s must be one of update, append, replace, got %q", setting("review.comment_strategy"), r.CommentStrategy)
+	}
+
+	if r.DryRun && r.RenderOnly {
+		p.add("%s cannot be combined with %s", setting("review.dry_run"), setting("review.render_only"))
+	}
+	if r.Output != "" && !r.DryRun && !r.RenderOnly {
+		p.add("%s requires %s or %s", setting("review.output"), setting("review.dry_run"), setting("review.render_only"))
+	}
+}
+
+// isSet reports whether the key was set by a flag, an environment variable or
+// a configuration file rather than left to its default.
+func (c *Config) isSet(name string) bool {
+	for _, source := range c.sources {
+		if source.Key == name {
+			return source.Origin != "default" && source.Origin != "unset"
+		}
+	}
+	return false
+}
+
+type problems []string
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *problems) required(name, value string) {
+	if value == "" {
+		p.add("%s is required", setting(name))
+	}
+}
+
+func (p *problems) url(name, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.add("%s must be an http or https URL, got %q", setting(name), value)
+	}
+}
+
+// setting names a key along with the environment variable and the flag that
+// set it, e.g. "llm.model (LLM_MODEL, --llm-model)".
+func setting(name string) string {
+	return fmt.Sprintf("%s (%s, --%s)", name, envName(name), flagName(name))
+}
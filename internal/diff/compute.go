@@ -0,0 +1,182 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Compute returns the hunks turning oldText into newText, with up to context
+// unchanged lines around each change, as git would write them. It finds a
+// shortest edit script with Myers' algorithm.
+func Compute(oldText, newText string, context int) []Hunk {
+	lines := edits(splitLines(oldText), splitLines(newText))
+
+	var hunks []Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == LineContext {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		// Extend the hunk over the following changes that are close enough
+		// for their contexts to meet.
+		for j := i; j < len(lines) && j <= end+2*context+1; j++ {
+			if lines[j].Kind != LineContext {
+				end = j
+			}
+		}
+		end = min(end+context+1, len(lines))
+
+		hunks = append(hunks, newHunk(lines, start, end))
+		i = end
+	}
+
+	return hunks
+}
+
+// newHunk returns the hunk made of lines[start:end].
+func newHunk(lines []Line, start, end int) Hunk {
+	hunk := Hunk{Lines: slices.Clone(lines[start:end])}
+
+	// A hunk without lines on one side starts at the line preceding it, or 0.
+	for _, line := range lines[:start] {
+		if line.Kind != LineAdded {
+			hunk.OldStart = line.OldLine
+		}
+		if line.Kind != LineDeleted {
+			hunk.NewStart = line.NewLine
+		}
+	}
+
+	for _, line := range hunk.Lines {
+		if line.Kind != LineAdded {
+			if hunk.OldLines == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+		if line.Kind != LineDeleted {
+			if hunk.NewLines == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+	}
+
+	return hunk
+}
+
+// maxEditDistance bounds the length of the edit script edits searches for.
+// The trace grows with its square, so files differing by more are shown as
+// deleted and added in full.
+const maxEditDistance = 1000
+
+// edits returns the lines of a and b as a sequence of context, deleted and
+// added lines, numbered on both sides.
+func edits(a, b []string) []Line {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds v[-d-1:d+2] as it was before the edits of length d were
+	// explored, which is all backtracking from them needs.
+	var trace [][]int
+
+	done := false
+	for d := 0; d <= min(n+m, maxEditDistance) && !done; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+
+		for k := -d; k <= d && !done; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			done = x >= n && y >= m
+		}
+	}
+
+	if !done {
+		return number(replaceAll(a, b))
+	}
+
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d+1] < prev[k+1+d+1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Kind: LineContext, Content: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, Line{Kind: LineAdded, Content: b[y-1]})
+		} else {
+			reversed = append(reversed, Line{Kind: LineDeleted, Content: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		reversed = append(reversed, Line{Kind: LineContext, Content: a[x-1]})
+	}
+	slices.Reverse(reversed)
+
+	return number(reversed)
+}
+
+// replaceAll returns every line of a as deleted, followed by every line of b
+// as added.
+func replaceAll(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, content := range a {
+		lines = append(lines, Line{Kind: LineDeleted, Content: content})
+	}
+	for _, content := range b {
+		lines = append(lines, Line{Kind: LineAdded, Content: content})
+	}
+	return lines
+}
+
+// number sets the line numbers of lines on both sides, in place.
+func number(lines []Line) []Line {
+	oldLine, newLine := 0, 0
+	for i := range lines {
+		line := &lines[i]
+		if line.Kind != LineAdded {
+			oldLine++
+			line.OldLine = oldLine
+		}
+		if line.Kind != LineDeleted {
+			newLine++
+			line.NewLine = newLine
+		}
+	}
+
+	return lines
+}
+
+// splitLines splits text into lines, without the newline ending the last one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,367 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type LineKind int
+
+const (
+	LineContext LineKind = iota
+	LineAdded
+	LineDeleted
+)
+
+// Line is a single line of a hunk. OldLine is zero for added lines and
+// NewLine is zero for deleted lines.
+type Line struct {
+	Kind    LineKind
+	Content string
+	OldLine int
+	NewLine int
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string
+	Lines    []Line
+}
+
+type File struct {
+	OldPath   string
+	NewPath   string
+	OldMode   string
+	NewMode   string
+	IsNew     bool
+	IsDeleted bool
+	IsRenamed bool
+	IsBinary  bool
+	Hunks     []Hunk
+}
+
+// Path returns the path of the file in the new version, or its old path when
+// the file was deleted.
+func (f *File) Path() string {
+	if f.IsDeleted || f.NewPath == "" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// IsModeChange reports whether the file mode changed.
+func (f *File) IsModeChange() bool {
+	return f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode
+}
+
+// Parse parses a unified diff made of one or more file diffs. It understands
+// the extended headers written by git ("diff --git", modes, renames, binary
+// markers) but also accepts plain "---"/"+++" file headers. Parsing is lenient:
+// unrecognized lines are skipped and a hunk cut short, e.g. by truncation, ends
+// where its content does.
+func Parse(raw string) []File {
+	p := &parser{}
+	for _, line := range strings.Split(raw, "\n") {
+		p.parseLine(strings.TrimSuffix(line, "\r"))
+	}
+	p.flush()
+
+	return p.files
+}
+
+// Format writes the files back as a unified diff in git format. Hunk headers
+// are recomputed from the hunk lines, so that a subset of the hunks of a file,
+// or a hunk cut short by truncation, still forms a valid diff.
+func Format(files []File) string {
+	var b strings.Builder
+	for i := range files {
+		files[i].format(&b)
+	}
+	return b.String()
+}
+
+func (f *File) format(b *strings.Builder) {
+	fmt.Fprintf(b, "diff --git a/%s b/%s\n", f.OldPath, f.NewPath)
+
+	switch {
+	case f.IsNew && f.NewMode != "":
+		fmt.Fprintf(b, "new file mode %s\n", f.NewMode)
+	case f.IsDeleted && f.OldMode != "":
+		fmt.Fprintf(b, "deleted file mode %s\n", f.OldMode)
+	case f.IsModeChange():
+		fmt.Fprintf(b, "old mode %s\nnew mode %s\n", f.OldMode, f.NewMode)
+	}
+
+	if f.IsRenamed {
+		fmt.Fprintf(b, "rename from %s\nrename to %s\n", f.OldPath, f.NewPath)
+	}
+
+	if f.IsBinary {
+		fmt.Fprintf(b, "Binary files a/%s and b/%s differ\n", f.OldPath, f.NewPath)
+		return
+	}
+
+	if len(f.Hunks) == 0 {
+		return
+	}
+
+	oldPath, newPath := "a/"+f.OldPath, "b/"+f.NewPath
+	if f.IsNew {
+		oldPath = "/dev/null"
+	}
+	if f.IsDeleted {
+		newPath = "/dev/null"
+	}
+	fmt.Fprintf(b, "--- %s\n+++ %s\n", oldPath, newPath)
+
+	for _, hunk := range f.Hunks {
+		hunk.format(b)
+	}
+}
+
+func (h *Hunk) format(b *strings.Builder) {
+	oldLines, newLines := 0, 0
+	for _, line := range h.Lines {
+		if line.Kind != LineAdded {
+			oldLines++
+		}
+		if line.Kind != LineDeleted {
+			newLines++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@", h.OldStart, oldLines, h.NewStart, newLines)
+	if h.Section != "" {
+		fmt.Fprintf(b, " %s", h.Section)
+	}
+	b.WriteString("\n")
+
+	for _, line := range h.Lines {
+		switch line.Kind {
+		case LineAdded:
+			b.WriteString("+")
+		case LineDeleted:
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line.Content)
+		b.WriteString("\n")
+	}
+}
+
+type parser struct {
+	files []File
+
+	file *File
+	hunk *Hunk
+
+	oldLine      int
+	newLine      int
+	oldRemaining int
+	newRemaining int
+}
+
+func (p *parser) parseLine(line string) {
+	if p.hunk != nil && (p.oldRemaining > 0 || p.newRemaining > 0) {
+		if p.parseHunkLine(line) {
+			return
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		p.startFile()
+		p.file.OldPath, p.file.NewPath = parseGitPaths(strings.TrimPrefix(line, "diff --git "))
+	case strings.HasPrefix(line, "--- "):
+		if p.file == nil || p.hunk != nil {
+			p.startFile()
+		}
+		if path, ok := trimPath(line[4:], "a/"); ok {
+			p.file.OldPath = path
+		} else {
+			p.file.IsNew = true
+		}
+	case strings.HasPrefix(line, "+++ ") && p.file != nil:
+		if path, ok := trimPath(line[4:], "b/"); ok {
+			p.file.NewPath = path
+		} else {
+			p.file.IsDeleted = true
+		}
+	case strings.HasPrefix(line, "@@ ") && p.file != nil:
+		p.startHunk(line)
+	case p.file == nil || p.hunk != nil:
+		return
+	case strings.HasPrefix(line, "old mode "):
+		p.file.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		p.file.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "new file mode "):
+		p.file.IsNew = true
+		p.file.NewMode = strings.TrimPrefix(line, "new file mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		p.file.IsDeleted = true
+		p.file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+	case strings.HasPrefix(line, "rename from "):
+		p.file.IsRenamed = true
+		p.file.OldPath = strings.TrimPrefix(line, "rename from ")
+	case strings.HasPrefix(line, "rename to "):
+		p.file.IsRenamed = true
+		p.file.NewPath = strings.TrimPrefix(line, "rename to ")
+	case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+		p.file.IsBinary = true
+	}
+}
+
+// parseHunkLine consumes a line of hunk content and reports whether the line
+// belonged to the hunk.
+func (p *parser) parseHunkLine(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "+"):
+		p.hunk.Lines = append(p.hunk.Lines, Line{Kind: LineAdded, Content: line[1:], NewLine: p.newLine})
+		p.newLine++
+		p.newRemaining--
+	case strings.HasPrefix(line, "-"):
+		p.hunk.Lines = append(p.hunk.Lines, Line{Kind: LineDeleted, Content: line[1:], OldLine: p.oldLine})
+		p.oldLine++
+		p.oldRemaining--
+	case strings.HasPrefix(line, " "), line == "":
+		// Some tools strip the trailing space of empty context lines.
+		content := strings.TrimPrefix(line, " ")
+		p.hunk.Lines = append(p.hunk.Lines, Line{Kind: LineContext, Content: content, OldLine: p.oldLine, NewLine: p.newLine})
+		p.oldLine++
+		p.newLine++
+		p.oldRemaining--
+		p.newRemaining--
+	case strings.HasPrefix(line, `\`):
+		// "\ No newline at end of file"
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (p *parser) startFile() {
+	p.flush()
+	p.file = &File{}
+}
+
+func (p *parser) startHunk(line string) {
+	p.flushHunk()
+
+	hunk, err := parseHunkHeader(line)
+	if err != nil {
+		return
+	}
+
+	p.hunk = &hunk
+	p.oldLine, p.newLine = hunk.OldStart, hunk.NewStart
+	p.oldRemaining, p.newRemaining = hunk.OldLines, hunk.NewLines
+}
+
+func (p *parser) flushHunk() {
+	if p.hunk != nil {
+		p.file.Hunks = append(p.file.Hunks, *p.hunk)
+		p.hunk = nil
+	}
+}
+
+func (p *parser) flush() {
+	if p.file == nil {
+		return
+	}
+	p.flushHunk()
+
+	if p.file.OldPath == "" {
+		p.file.OldPath = p.file.NewPath
+	}
+	if p.file.NewPath == "" {
+		p.file.NewPath = p.file.OldPath
+	}
+
+	p.files = append(p.files, *p.file)
+	p.file = nil
+}
+
+// parseGitPaths extracts the paths of a "diff --git a/<old> b/<new>" line.
+// Paths containing " b/" are ambiguous there; the "---"/"+++" or rename
+// headers that follow take precedence when present.
+func parseGitPaths(paths string) (string, string) {
+	idx := strings.LastIndex(paths, " b/")
+	if idx == -1 {
+		return "", ""
+	}
+
+	return strings.TrimPrefix(paths[:idx], "a/"), paths[idx+len(" b/"):]
+}
+
+func trimPath(path, prefix string) (string, bool) {
+	// Paths may be followed by a tab and a timestamp.
+	path, _, _ = strings.Cut(path, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return "", false
+	}
+	return strings.TrimPrefix(path, prefix), true
+}
+
+// parseHunkHeader parses a hunk header such as "@@ -10,7 +10,8 @@ func main() {".
+func parseHunkHeader(line string) (Hunk, error) {
+	ranges, section, ok := strings.Cut(strings.TrimPrefix(line, "@@ "), " @@")
+	if !ok {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	oldRange, newRange, ok := strings.Cut(ranges, " ")
+	if !ok {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	oldStart, oldLines, err := parseRange(oldRange, "-")
+	if err != nil {
+		return Hunk{}, err
+	}
+
+	newStart, newLines, err := parseRange(newRange, "+")
+	if err != nil {
+		return Hunk{}, err
+	}
+
+	return Hunk{
+		OldStart: oldStart,
+		OldLines: oldLines,
+		NewStart: newStart,
+		NewLines: newLines,
+		Section:  strings.TrimSpace(section),
+	}, nil
+}
+
+// parseRange parses "-start,count" or "+start,count". The count defaults to
+// one when omitted.
+func parseRange(field, prefix string) (int, int, error) {
+	if !strings.HasPrefix(field, prefix) {
+		return 0, 0, fmt.Errorf("malformed hunk range: %q", field)
+	}
+
+	startStr, countStr, hasCount := strings.Cut(field[1:], ",")
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk range %q: %w", field, err)
+	}
+
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed hunk range %q: %w", field, err)
+		}
+	}
+
+	return start, count, nil
+}
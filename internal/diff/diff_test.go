@@ -0,0 +1,291 @@
+package diff_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff_Parse(t *testing.T) {
+	t.Run("Success_ParseModifiedFile", func(t *testing.T) {
+		raw := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@ package main
+ import "fmt"
+-func old() {}
++func new() {}
+ var x = 1
+@@ -10 +10,2 @@
+-a
++b
++c
+\ No newline at end of file
+`
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 1)
+		file := files[0]
+		assert.Equal(t, "main.go", file.OldPath)
+		assert.Equal(t, "main.go", file.NewPath)
+		assert.Equal(t, "main.go", file.Path())
+		assert.False(t, file.IsNew)
+		assert.False(t, file.IsDeleted)
+		assert.False(t, file.IsRenamed)
+		assert.False(t, file.IsBinary)
+
+		require.Len(t, file.Hunks, 2)
+		assert.Equal(t, diff.Hunk{
+			OldStart: 1,
+			OldLines: 3,
+			NewStart: 1,
+			NewLines: 3,
+			Section:  "package main",
+			Lines: []diff.Line{
+				{Kind: diff.LineContext, Content: `import "fmt"`, OldLine: 1, NewLine: 1},
+				{Kind: diff.LineDeleted, Content: "func old() {}", OldLine: 2},
+				{Kind: diff.LineAdded, Content: "func new() {}", NewLine: 2},
+				{Kind: diff.LineContext, Content: "var x = 1", OldLine: 3, NewLine: 3},
+			},
+		}, file.Hunks[0])
+
+		assert.Equal(t, 1, file.Hunks[1].OldLines)
+		assert.Equal(t, []diff.Line{
+			{Kind: diff.LineDeleted, Content: "a", OldLine: 10},
+			{Kind: diff.LineAdded, Content: "b", NewLine: 10},
+			{Kind: diff.LineAdded, Content: "c", NewLine: 11},
+		}, file.Hunks[1].Lines)
+	})
+
+	t.Run("Success_ParseNewDeletedAndRenamedFiles", func(t *testing.T) {
+		raw := `diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 1111111..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/old name.go b/new name.go
+similarity index 100%
+rename from old name.go
+rename to new name.go
+`
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 3)
+
+		assert.True(t, files[0].IsNew)
+		assert.Equal(t, "100644", files[0].NewMode)
+		assert.Equal(t, "new.go", files[0].Path())
+		assert.Equal(t, []diff.Line{{Kind: diff.LineAdded, Content: "package main", NewLine: 1}}, files[0].Hunks[0].Lines)
+
+		assert.True(t, files[1].IsDeleted)
+		assert.Equal(t, "100644", files[1].OldMode)
+		assert.Equal(t, "gone.go", files[1].Path())
+		assert.Equal(t, []diff.Line{{Kind: diff.LineDeleted, Content: "package main", OldLine: 1}}, files[1].Hunks[0].Lines)
+
+		assert.True(t, files[2].IsRenamed)
+		assert.Equal(t, "old name.go", files[2].OldPath)
+		assert.Equal(t, "new name.go", files[2].NewPath)
+		assert.Empty(t, files[2].Hunks)
+	})
+
+	t.Run("Success_ParseBinaryAndModeChange", func(t *testing.T) {
+		raw := `diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 2)
+		assert.True(t, files[0].IsBinary)
+		assert.Equal(t, "logo.png", files[0].Path())
+		assert.False(t, files[0].IsModeChange())
+
+		assert.True(t, files[1].IsModeChange())
+		assert.Equal(t, "100644", files[1].OldMode)
+		assert.Equal(t, "100755", files[1].NewMode)
+	})
+
+	t.Run("Success_ParseDeletedLineLookingLikeHeader", func(t *testing.T) {
+		raw := `--- a/notes.md
++++ b/notes.md
+@@ -1,2 +1 @@
+--- a list item
+ text
+--- a/other.md
++++ b/other.md
+@@ -1 +1 @@
+-x
++y
+`
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 2)
+		assert.Equal(t, "notes.md", files[0].Path())
+		assert.Equal(t, []diff.Line{
+			{Kind: diff.LineDeleted, Content: "-- a list item", OldLine: 1},
+			{Kind: diff.LineContext, Content: "text", OldLine: 2, NewLine: 1},
+		}, files[0].Hunks[0].Lines)
+		assert.Equal(t, "other.md", files[1].Path())
+	})
+
+	t.Run("Success_ParseTruncatedDiff", func(t *testing.T) {
+		raw := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,10 +1,10 @@\n line\n-old\n+ne"
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 1)
+		require.Len(t, files[0].Hunks, 1)
+		assert.Len(t, files[0].Hunks[0].Lines, 3)
+	})
+
+	t.Run("Success_SkipMalformedHunkHeader", func(t *testing.T) {
+		raw := "--- a/main.go\n+++ b/main.go\n@@ -x +1 @@\n+new\n"
+
+		files := diff.Parse(raw)
+
+		require.Len(t, files, 1)
+		assert.Empty(t, files[0].Hunks)
+	})
+
+	t.Run("Success_ParseEmptyDiff", func(t *testing.T) {
+		assert.Empty(t, diff.Parse(""))
+	})
+}
+
+func TestDiff_Format(t *testing.T) {
+	t.Run("Success_RoundTrip", func(t *testing.T) {
+		raw := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@ package main\n import \"fmt\"\n-func old() {}\n+func new() {}\n \n" +
+			"diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,1 @@\n+package main\n" +
+			"diff --git a/old.go b/old.go\ndeleted file mode 100644\n--- a/old.go\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-package main\n" +
+			"diff --git a/a.txt b/b.txt\nrename from a.txt\nrename to b.txt\n" +
+			"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n" +
+			"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n"
+
+		formatted := diff.Format(diff.Parse(raw))
+
+		assert.Equal(t, raw, formatted)
+	})
+
+	t.Run("Success_RecomputeHunkHeader", func(t *testing.T) {
+		files := []diff.File{{
+			OldPath: "main.go",
+			NewPath: "main.go",
+			Hunks: []diff.Hunk{{
+				OldStart: 10,
+				OldLines: 7,
+				NewStart: 10,
+				NewLines: 8,
+				Lines: []diff.Line{
+					{Kind: diff.LineContext, Content: "func main() {", OldLine: 10, NewLine: 10},
+					{Kind: diff.LineAdded, Content: "\tinit()", NewLine: 11},
+				},
+			}},
+		}}
+
+		formatted := diff.Format(files)
+
+		assert.Equal(t, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -10,1 +10,2 @@\n func main() {\n+\tinit()\n", formatted)
+	})
+}
+
+func TestDiff_Compute(t *testing.T) {
+	format := func(oldText, newText string) string {
+		return diff.Format([]diff.File{{OldPath: "f.txt", NewPath: "f.txt", Hunks: diff.Compute(oldText, newText, 3)}})
+	}
+
+	t.Run("Success_SeparateHunks", func(t *testing.T) {
+		oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+		newText := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n"
+
+		assert.Equal(t, "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n"+
+			"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n"+
+			"@@ -11,5 +11,5 @@\n 11\n 12\n 13\n-14\n 15\n+16\n", format(oldText, newText))
+	})
+
+	t.Run("Success_MergeCloseChanges", func(t *testing.T) {
+		hunks := diff.Compute("a\nb\nc\nd\ne\nf\ng\nh\n", "A\nb\nc\nd\ne\nf\ng\nH\n", 3)
+
+		require.Len(t, hunks, 1)
+		assert.Equal(t, diff.Hunk{OldStart: 1, OldLines: 8, NewStart: 1, NewLines: 8, Lines: hunks[0].Lines}, hunks[0])
+		assert.Equal(t, diff.Line{Kind: diff.LineAdded, Content: "H", NewLine: 8}, hunks[0].Lines[len(hunks[0].Lines)-1])
+	})
+
+	t.Run("Success_NewAndDeletedFiles", func(t *testing.T) {
+		added := diff.Compute("", "package main\nfunc main() {}\n", 3)
+		deleted := diff.Compute("package main\n", "", 3)
+
+		require.Len(t, added, 1)
+		assert.Equal(t, 0, added[0].OldStart)
+		assert.Equal(t, 1, added[0].NewStart)
+		assert.Equal(t, 2, added[0].NewLines)
+
+		require.Len(t, deleted, 1)
+		assert.Equal(t, 1, deleted[0].OldStart)
+		assert.Equal(t, 0, deleted[0].NewStart)
+		assert.Equal(t, 1, deleted[0].OldLines)
+	})
+
+	t.Run("Success_InsertionWithoutContext", func(t *testing.T) {
+		hunks := diff.Compute("a\nb\n", "a\nnew\nb\n", 0)
+
+		require.Len(t, hunks, 1)
+		assert.Equal(t, diff.Hunk{OldStart: 1, NewStart: 2, NewLines: 1, Lines: []diff.Line{{Kind: diff.LineAdded, Content: "new", NewLine: 2}}}, hunks[0])
+	})
+
+	t.Run("Success_Identical", func(t *testing.T) {
+		assert.Empty(t, diff.Compute("a\nb\n", "a\nb\n", 3))
+	})
+
+	t.Run("Success_RewriteBeyondEditLimit", func(t *testing.T) {
+		var oldText, newText strings.Builder
+		for i := 1; i <= 4000; i++ {
+			fmt.Fprintf(&oldText, "old %d\n", i)
+			fmt.Fprintf(&newText, "new %d\n", i)
+		}
+
+		hunks := diff.Compute(oldText.String(), newText.String(), 3)
+
+		require.Len(t, hunks, 1)
+		assert.Equal(t, diff.Hunk{OldStart: 1, OldLines: 4000, NewStart: 1, NewLines: 4000, Lines: hunks[0].Lines}, hunks[0])
+		assert.Equal(t, diff.Line{Kind: diff.LineDeleted, Content: "old 1", OldLine: 1}, hunks[0].Lines[0])
+		assert.Equal(t, diff.Line{Kind: diff.LineAdded, Content: "new 1", NewLine: 1}, hunks[0].Lines[4000])
+	})
+
+	t.Run("Success_RoundTripThroughParse", func(t *testing.T) {
+		oldText := "alpha\nbeta\ngamma\ndelta\nepsilon\n"
+		newText := "alpha\ngamma\ndelta\nzeta\nepsilon\neta\n"
+
+		files := diff.Parse(format(oldText, newText))
+
+		require.Len(t, files, 1)
+		require.Len(t, files[0].Hunks, 1)
+		var rebuilt []string
+		for _, line := range files[0].Hunks[0].Lines {
+			if line.Kind != diff.LineDeleted {
+				rebuilt = append(rebuilt, line.Content)
+			}
+		}
+		assert.Equal(t, []string{"alpha", "gamma", "delta", "zeta", "epsilon", "eta"}, rebuilt)
+	})
+}
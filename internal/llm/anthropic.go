@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/retry"
+)
+
+const (
+	// DefaultAnthropicBaseURL is the base URL of the Anthropic API.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	// AnthropicVersion is the Anthropic API version the driver speaks.
+	AnthropicVersion = "2023-06-01"
+)
+
+// defaultAnthropicMaxTokens is used when the request does not set a maximum,
+// since the Messages API requires one.
+const defaultAnthropicMaxTokens = 4096
+
+// anthropicToolName is the name of the tool the model is forced to call to
+// return a response matching the requested schema.
+const anthropicToolName = "response"
+
+// anthropicJSONInstruction asks for a JSON response when no schema is given,
+// since the Messages API has no JSON mode.
+const anthropicJSONInstruction = "Respond with a single JSON value only, without Markdown fences or any other text."
+
+type AnthropicDriver struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// NewAnthropicDriver creates a driver for the Anthropic Messages API at
+// baseURL, or at the Anthropic API when baseURL is empty.
+func NewAnthropicDriver(httpClient *http.Client, apiKey, baseURL string) (*AnthropicDriver, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("api key is required for the Anthropic API")
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultAnthropicBaseURL
+	}
+
+	return &AnthropicDriver{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	InputSchema *Schema `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicMessagesRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float32              `json:"temperature"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type anthropicMessagesResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (d *AnthropicDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	msgReq := anthropicMessagesRequest{
+		Model:       req.Model,
+		System:      req.System,
+		Messages:    []anthropicMessage{{Role: "user", Content: req.Prompt}},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+
+	if msgReq.MaxTokens <= 0 {
+		msgReq.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	switch {
+	case req.ResponseSchema != nil:
+		// The Messages API constrains output to a schema through tool inputs,
+		// so the model is made to call a tool whose input is the response.
+		msgReq.Tools = []anthropicTool{{
+			Name:        anthropicToolName,
+			Description: "Return the response.",
+			InputSchema: req.ResponseSchema,
+		}}
+		msgReq.ToolChoice = &anthropicToolChoice{Type: "tool", Name: anthropicToolName}
+	case req.ResponseMIMEType == "application/json":
+		msgReq.System = strings.TrimSpace(msgReq.System + "\n\n" + anthropicJSONInstruction)
+	}
+
+	body, err := json.Marshal(msgReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode messages request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Api-Key", d.apiKey)
+	httpReq.Header.Set("Anthropic-Version", AnthropicVersion)
+
+	res, err := d.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Messages API: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := &APIError{
+			API:        "Messages",
+			StatusCode: res.StatusCode,
+			RetryAfter: retry.ParseRetryAfter(res.Header),
+		}
+
+		var errResp anthropicErrorResponse
+		if json.Unmarshal(resBody, &errResp) == nil {
+			apiErr.Type = errResp.Error.Type
+			apiErr.Message = errResp.Error.Message
+		}
+
+		return nil, apiErr
+	}
+
+	var msgResp anthropicMessagesResponse
+	if err := json.Unmarshal(resBody, &msgResp); err != nil {
+		return nil, fmt.Errorf("failed to decode messages response: %w", err)
+	}
+
+	if req.ResponseSchema != nil {
+		for _, block := range msgResp.Content {
+			if block.Type == "tool_use" && block.Name == anthropicToolName {
+				return &GenerateResponse{Content: string(block.Input)}, nil
+			}
+		}
+		return nil, fmt.Errorf("messages response has no %s tool call (stop reason: %s)", anthropicToolName, msgResp.StopReason)
+	}
+
+	var content strings.Builder
+	for _, block := range msgResp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	return &GenerateResponse{
+		Content: content.String(),
+	}, nil
+}
@@ -0,0 +1,253 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicDriver_NewAnthropicDriver(t *testing.T) {
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := llm.NewAnthropicDriver(http.DefaultClient, "fake-api-key", "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingAPIKey", func(t *testing.T) {
+		driver, err := llm.NewAnthropicDriver(http.DefaultClient, "", "")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "api key is required for the Anthropic API")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestAnthropicDriver_Generate(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(t *testing.T, handler func(t *testing.T, body map[string]any, w http.ResponseWriter)) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/messages", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "fake-api-key", r.Header.Get("x-api-key"))
+			assert.Equal(t, llm.AnthropicVersion, r.Header.Get("anthropic-version"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			handler(t, body, w)
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	t.Run("Success_GenerateContent", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, "claude-sonnet-4-5", body["model"])
+			assert.Equal(t, "You are a careful reviewer.", body["system"])
+			assert.Equal(t, []any{map[string]any{"role": "user", "content": "Say 'OK'"}}, body["messages"])
+			assert.InDelta(t, 0.2, body["temperature"], 0.001)
+			assert.Equal(t, float64(128), body["max_tokens"])
+			assert.NotContains(t, body, "tools")
+
+			fmt.Fprint(w, `{"content": [{"type": "text", "text": "O"}, {"type": "text", "text": "K"}], "stop_reason": "end_turn"}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL+"/")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "claude-sonnet-4-5",
+			System:           "You are a careful reviewer.",
+			Prompt:           "Say 'OK'",
+			Temperature:      0.2,
+			MaxTokens:        128,
+			ResponseMIMEType: "text/plain",
+		})
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "OK", res.Content)
+	})
+
+	t.Run("Success_DefaultMaxTokens", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, float64(4096), body["max_tokens"])
+			assert.NotContains(t, body, "system")
+
+			fmt.Fprint(w, `{"content": [{"type": "text", "text": "OK"}]}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "claude-sonnet-4-5", Prompt: "Say 'OK'"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "OK", res.Content)
+	})
+
+	t.Run("Success_GenerateWithResponseSchema", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, []any{map[string]any{
+				"name":        "response",
+				"description": "Return the response.",
+				"input_schema": map[string]any{
+					"type":       "object",
+					"required":   []any{"status"},
+					"properties": map[string]any{"status": map[string]any{"type": "string", "enum": []any{"OK", "FAIL"}}},
+				},
+			}}, body["tools"])
+			assert.Equal(t, map[string]any{"type": "tool", "name": "response"}, body["tool_choice"])
+
+			fmt.Fprint(w, `{"content": [{"type": "tool_use", "id": "toolu_1", "name": "response", "input": {"status": "OK"}}], "stop_reason": "tool_use"}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "claude-sonnet-4-5",
+			Prompt:           "Reply with status OK",
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"status"},
+				Properties: map[string]*llm.Schema{
+					"status": {Type: llm.TypeString, Enum: []string{"OK", "FAIL"}},
+				},
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"status": "OK"}`, res.Content)
+	})
+
+	t.Run("Success_GenerateJSONWithoutSchema", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Contains(t, body["system"], "Respond with a single JSON value only")
+			assert.NotContains(t, body, "tools")
+
+			fmt.Fprint(w, `{"content": [{"type": "text", "text": "{}"}]}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "claude-sonnet-4-5",
+			Prompt:           "Reply with an empty object",
+			ResponseMIMEType: "application/json",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", res.Content)
+	})
+
+	t.Run("Failure_Overloaded", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			w.WriteHeader(529)
+			fmt.Fprint(w, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "claude-sonnet-4-5", Prompt: "Say 'OK'"})
+
+		assert.ErrorIs(t, err, llm.ErrOverloaded)
+		assert.NotErrorIs(t, err, llm.ErrRateLimited)
+		assert.Contains(t, err.Error(), "Messages API returned status 529: Overloaded")
+
+		var apiErr *llm.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.True(t, apiErr.Retryable())
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_RateLimited", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"type": "error", "error": {"type": "rate_limit_error", "message": "Number of request tokens has exceeded your per-minute rate limit"}}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "claude-sonnet-4-5", Prompt: "Say 'OK'"})
+
+		assert.ErrorIs(t, err, llm.ErrRateLimited)
+
+		var apiErr *llm.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 30*time.Second, apiErr.RetryAfter)
+		assert.True(t, apiErr.Retryable())
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_Unauthorized", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "claude-sonnet-4-5", Prompt: "Say 'OK'"})
+
+		var apiErr *llm.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "authentication_error", apiErr.Type)
+		assert.False(t, apiErr.Retryable())
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_MissingToolCall", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprint(w, `{"content": [{"type": "text", "text": "I cannot do that."}], "stop_reason": "max_tokens"}`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:          "claude-sonnet-4-5",
+			Prompt:         "Reply with status OK",
+			ResponseSchema: &llm.Schema{Type: llm.TypeObject},
+		})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "messages response has no response tool call (stop reason: max_tokens)")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_MalformedResponse", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprint(w, `not json`)
+		})
+
+		driver, err := llm.NewAnthropicDriver(server.Client(), "fake-api-key", server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "claude-sonnet-4-5", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode messages response")
+		assert.Nil(t, res)
+	})
+}
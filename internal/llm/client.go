@@ -3,35 +3,105 @@ package llm
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/retry"
 )
 
 type client struct {
 	driver Driver
 	cfg    config.LLM
+	policy retry.Policy
+
+	mu     sync.Mutex
+	models []string
 }
 
 func NewClient(driver Driver, cfg config.LLM) Client {
 	return &client{
 		driver: driver,
 		cfg:    cfg,
+		policy: retry.NewPolicy(cfg.Retry),
 	}
 }
 
 func (c *client) GenerateContent(ctx context.Context, prompt string) (string, error) {
 	req := GenerateRequest{
 		Model:            c.cfg.Model,
+		System:           c.cfg.SystemPrompt,
 		Prompt:           prompt,
 		Temperature:      c.cfg.Temperature,
 		MaxTokens:        c.cfg.MaxTokens,
 		ResponseMIMEType: "text/plain",
 	}
 
-	resp, err := c.driver.Generate(ctx, req)
+	resp, err := c.generate(ctx, "generate content", req)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate content using LLM driver: %w", err)
 	}
 
+	c.recordModel(resp)
+
 	return resp.Content, nil
 }
+
+func (c *client) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	req := GenerateRequest{
+		Model:            c.cfg.Model,
+		System:           c.cfg.SystemPrompt,
+		Prompt:           prompt,
+		Temperature:      c.cfg.Temperature,
+		MaxTokens:        c.cfg.MaxTokens,
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   schema,
+	}
+
+	resp, err := c.generate(ctx, "generate JSON content", req)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate JSON content using LLM driver: %w", err)
+	}
+
+	c.recordModel(resp)
+
+	return resp.Content, nil
+}
+
+// generate calls the driver, retrying transient failures according to the
+// retry policy.
+func (c *client) generate(ctx context.Context, op string, req GenerateRequest) (*GenerateResponse, error) {
+	var resp *GenerateResponse
+	err := retry.Do(ctx, c.policy, op, ClassifyError, func() error {
+		var err error
+		resp, err = c.driver.Generate(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *client) Models() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.models)
+}
+
+// recordModel remembers the model that produced resp, which is the configured
+// one unless the driver reports otherwise.
+func (c *client) recordModel(resp *GenerateResponse) {
+	model := resp.Model
+	if model == "" {
+		model = c.cfg.Model
+		if c.cfg.Provider != "" {
+			model = string(c.cfg.Provider) + "/" + model
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !slices.Contains(c.models, model) {
+		c.models = append(c.models, model)
+	}
+}
@@ -3,7 +3,9 @@ package llm_test
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/llm"
@@ -76,3 +78,144 @@ func TestClient_GenerateContent(t *testing.T) {
 		mockDriver.AssertExpectations(t)
 	})
 }
+
+func TestClient_GenerateJSON(t *testing.T) {
+	schema := &llm.Schema{
+		Type:     llm.TypeObject,
+		Required: []string{"summary"},
+		Properties: map[string]*llm.Schema{
+			"summary": {Type: llm.TypeString},
+		},
+	}
+
+	t.Run("Success_SuccessGenerateJSON", func(t *testing.T) {
+		cfg := config.Config{
+			LLM: config.LLM{Model: "gemini-2.5-flash", SystemPrompt: "You are a reviewer.", Temperature: 0.2, MaxTokens: 1024},
+		}
+
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("Generate", mock.Anything, llm.GenerateRequest{
+			Model:            "gemini-2.5-flash",
+			System:           "You are a reviewer.",
+			Prompt:           "Hi, I am a prompt",
+			Temperature:      0.2,
+			MaxTokens:        1024,
+			ResponseMIMEType: "application/json",
+			ResponseSchema:   schema,
+		}).Return(&llm.GenerateResponse{
+			Content: `{"summary": "Looks Good To Me!"}`,
+		}, nil)
+
+		client := llm.NewClient(mockDriver, cfg.LLM)
+
+		content, err := client.GenerateJSON(context.Background(), "Hi, I am a prompt", schema)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"summary": "Looks Good To Me!"}`, content)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToGenerateJSON", func(t *testing.T) {
+		cfg := config.Config{}
+
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, fmt.Errorf("failed to generate content"))
+
+		client := llm.NewClient(mockDriver, cfg.LLM)
+
+		content, err := client.GenerateJSON(context.Background(), "Hi, I am a prompt", schema)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to generate JSON content using LLM driver")
+		assert.Empty(t, content)
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_Models(t *testing.T) {
+	t.Run("Success_ConfiguredModel", func(t *testing.T) {
+		cfg := config.Config{
+			LLM: config.LLM{Provider: config.ProviderGemini, Model: "gemini-2.5-flash"},
+		}
+
+		mockDriver := new(MockDriver)
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(&llm.GenerateResponse{Content: "Looks Good To Me!"}, nil)
+
+		client := llm.NewClient(mockDriver, cfg.LLM)
+
+		assert.Empty(t, client.Models())
+
+		_, err := client.GenerateContent(context.Background(), "Hi, I am a prompt")
+		assert.NoError(t, err)
+		_, err = client.GenerateContent(context.Background(), "Hi, I am another prompt")
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"gemini/gemini-2.5-flash"}, client.Models())
+	})
+
+	t.Run("Success_ModelsChosenByDriver", func(t *testing.T) {
+		cfg := config.Config{
+			LLM: config.LLM{Provider: config.ProviderGemini, Model: "gemini-2.5-flash"},
+		}
+
+		mockDriver := new(MockDriver)
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(&llm.GenerateResponse{Content: "{}", Model: "anthropic/claude-sonnet-4-5"}, nil).Once()
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(&llm.GenerateResponse{Content: "{}"}, nil).Once()
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, fmt.Errorf("failed to generate content")).Once()
+
+		client := llm.NewClient(mockDriver, cfg.LLM)
+
+		for range 3 {
+			_, _ = client.GenerateJSON(context.Background(), "Hi, I am a prompt", nil)
+		}
+
+		assert.Equal(t, []string{"anthropic/claude-sonnet-4-5", "gemini/gemini-2.5-flash"}, client.Models())
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_Retry(t *testing.T) {
+	cfg := config.LLM{
+		Model: "claude-sonnet-4-5",
+		Retry: config.Retry{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+
+	t.Run("Success_RetryOverloaded", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Messages", StatusCode: 529, Type: "overloaded_error"}).Twice()
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(&llm.GenerateResponse{Content: "Looks Good To Me!"}, nil).Once()
+
+		client := llm.NewClient(mockDriver, cfg)
+
+		content, err := client.GenerateContent(context.Background(), "Hi, I am a prompt")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Looks Good To Me!", content)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_DoNotRetryAuthError", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Messages", StatusCode: http.StatusUnauthorized, Type: "authentication_error"})
+
+		client := llm.NewClient(mockDriver, cfg)
+
+		content, err := client.GenerateJSON(context.Background(), "Hi, I am a prompt", nil)
+
+		assert.Error(t, err)
+		assert.Empty(t, content)
+		mockDriver.AssertNumberOfCalls(t, "Generate", 1)
+	})
+}
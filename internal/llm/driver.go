@@ -8,12 +8,17 @@ type Driver interface {
 
 type GenerateRequest struct {
 	Model            string
+	System           string
 	Prompt           string
 	ResponseMIMEType string
+	ResponseSchema   *Schema
 	Temperature      float32
 	MaxTokens        int
 }
 
 type GenerateResponse struct {
 	Content string
+	// Model names the model that produced the content when the driver chose
+	// it, as the fallback driver does.
+	Model string
 }
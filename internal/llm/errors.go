@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrRateLimited matches API errors caused by exceeding a rate limit or
+	// quota.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrOverloaded matches API errors caused by the provider being
+	// temporarily overloaded.
+	ErrOverloaded = errors.New("overloaded")
+)
+
+// APIError is an error response returned by an LLM provider's API.
+type APIError struct {
+	// API names the API that returned the error, e.g. "Messages".
+	API        string
+	StatusCode int
+	// Type is the provider's error type, e.g. "overloaded_error".
+	Type    string
+	Message string
+	// RetryAfter is how long the provider asked to wait before retrying, or
+	// zero when it did not say.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s API returned status %d", e.API, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.API, e.StatusCode, e.Message)
+}
+
+// Is makes rate limit and overload errors match ErrRateLimited and
+// ErrOverloaded.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error"
+	case ErrOverloaded:
+		return e.StatusCode == statusOverloaded || e.StatusCode == http.StatusServiceUnavailable || e.Type == "overloaded_error"
+	default:
+		return false
+	}
+}
+
+// Retryable reports whether the same request may succeed later: rate limits,
+// overloads and server errors are transient, other client errors are not.
+func (e *APIError) Retryable() bool {
+	return errors.Is(e, ErrRateLimited) || errors.Is(e, ErrOverloaded) || e.StatusCode >= http.StatusInternalServerError
+}
+
+// statusOverloaded is the non-standard status code Anthropic uses when its API
+// is overloaded.
+const statusOverloaded = 529
+
+// IsRetryable reports whether err is a transient failure that another
+// attempt, or another provider, may not run into: a retryable API error or a
+// network error.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ClassifyError is the retry classifier of LLM calls: retryable errors are
+// retried after the delay the provider asked for, if any.
+func ClassifyError(err error) (bool, time.Duration) {
+	if !IsRetryable(err) {
+		return false, 0
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return true, apiErr.RetryAfter
+	}
+	return true, 0
+}
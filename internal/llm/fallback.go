@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// FallbackEntry is one provider and model in a fallback chain.
+type FallbackEntry struct {
+	// Name identifies the entry in logs and in the model reported by the
+	// response, e.g. "anthropic/claude-sonnet-4-5".
+	Name   string
+	Model  string
+	Driver Driver
+}
+
+// FallbackDriver tries a chain of drivers in order, moving on to the next one
+// when a request fails with a retryable error.
+type FallbackDriver struct {
+	entries []FallbackEntry
+}
+
+func NewFallbackDriver(entries []FallbackEntry) (*FallbackDriver, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("fallback chain has no entries")
+	}
+
+	return &FallbackDriver{entries: entries}, nil
+}
+
+// Generate sends the request to each entry in turn, with the entry's model,
+// until one succeeds. The response reports the name of the entry that
+// produced it. Errors that are not retryable are returned immediately.
+func (d *FallbackDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	var errs []error
+
+	for i, entry := range d.entries {
+		req.Model = entry.Model
+
+		resp, err := entry.Driver.Generate(ctx, req)
+		if err == nil {
+			resp.Model = entry.Name
+			return resp, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", entry.Name, err))
+
+		if ctx.Err() != nil || !IsRetryable(err) || i == len(d.entries)-1 {
+			break
+		}
+
+		slog.Warn("LLM request failed, falling back", "model", entry.Name, "next", d.entries[i+1].Name, "error", err)
+	}
+
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package llm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackDriver_NewFallbackDriver(t *testing.T) {
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := llm.NewFallbackDriver([]llm.FallbackEntry{
+			{Name: "gemini/gemini-2.5-flash", Model: "gemini-2.5-flash", Driver: new(MockDriver)},
+		})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_NoEntries", func(t *testing.T) {
+		driver, err := llm.NewFallbackDriver(nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "fallback chain has no entries")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestFallbackDriver_Generate(t *testing.T) {
+	ctx := context.Background()
+	req := llm.GenerateRequest{Model: "gemini-2.5-pro", Prompt: "Say 'OK'"}
+
+	withModel := func(model string) any {
+		return mock.MatchedBy(func(r llm.GenerateRequest) bool {
+			return r.Model == model && r.Prompt == "Say 'OK'"
+		})
+	}
+
+	newDriver := func(t *testing.T) (*llm.FallbackDriver, *MockDriver, *MockDriver) {
+		t.Helper()
+
+		primary, secondary := new(MockDriver), new(MockDriver)
+		driver, err := llm.NewFallbackDriver([]llm.FallbackEntry{
+			{Name: "gemini/gemini-2.5-pro", Model: "gemini-2.5-pro", Driver: primary},
+			{Name: "anthropic/claude-sonnet-4-5", Model: "claude-sonnet-4-5", Driver: secondary},
+		})
+		require.NoError(t, err)
+
+		return driver, primary, secondary
+	}
+
+	t.Run("Success_PrimaryModel", func(t *testing.T) {
+		driver, primary, secondary := newDriver(t)
+
+		primary.On("Generate", mock.Anything, withModel("gemini-2.5-pro")).
+			Return(&llm.GenerateResponse{Content: "OK"}, nil)
+
+		res, err := driver.Generate(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "OK", res.Content)
+		assert.Equal(t, "gemini/gemini-2.5-pro", res.Model)
+		primary.AssertExpectations(t)
+		secondary.AssertNotCalled(t, "Generate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success_FallBackOnRetryableError", func(t *testing.T) {
+		driver, primary, secondary := newDriver(t)
+
+		primary.On("Generate", mock.Anything, withModel("gemini-2.5-pro")).
+			Return(nil, fmt.Errorf("failed to generate content from Gemini API: %w", &llm.APIError{API: "Gemini", StatusCode: http.StatusTooManyRequests}))
+		secondary.On("Generate", mock.Anything, withModel("claude-sonnet-4-5")).
+			Return(&llm.GenerateResponse{Content: "OK"}, nil)
+
+		res, err := driver.Generate(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "OK", res.Content)
+		assert.Equal(t, "anthropic/claude-sonnet-4-5", res.Model)
+		primary.AssertExpectations(t)
+		secondary.AssertExpectations(t)
+	})
+
+	t.Run("Failure_NonRetryableError", func(t *testing.T) {
+		driver, primary, secondary := newDriver(t)
+
+		primary.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Gemini", StatusCode: http.StatusBadRequest, Message: "invalid argument"})
+
+		res, err := driver.Generate(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "gemini/gemini-2.5-pro: Gemini API returned status 400: invalid argument")
+		assert.Nil(t, res)
+		secondary.AssertNotCalled(t, "Generate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure_AllModelsFailed", func(t *testing.T) {
+		driver, primary, secondary := newDriver(t)
+
+		primary.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Gemini", StatusCode: http.StatusServiceUnavailable})
+		secondary.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Messages", StatusCode: 529, Type: "overloaded_error", Message: "Overloaded"})
+
+		res, err := driver.Generate(ctx, req)
+
+		assert.ErrorIs(t, err, llm.ErrOverloaded)
+		assert.Contains(t, err.Error(), "gemini/gemini-2.5-pro: Gemini API returned status 503")
+		assert.Contains(t, err.Error(), "anthropic/claude-sonnet-4-5: Messages API returned status 529: Overloaded")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_ContextCancelled", func(t *testing.T) {
+		driver, primary, secondary := newDriver(t)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		primary.On("Generate", mock.Anything, mock.Anything).
+			Return(nil, &llm.APIError{API: "Gemini", StatusCode: http.StatusServiceUnavailable})
+
+		res, err := driver.Generate(cancelled, req)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		secondary.AssertNotCalled(t, "Generate", mock.Anything, mock.Anything)
+	})
+}
@@ -2,11 +2,17 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"cloud.google.com/go/auth/credentials"
 	"google.golang.org/genai"
 )
 
+// vertexAIScope is the OAuth scope needed to call Vertex AI.
+const vertexAIScope = "https://www.googleapis.com/auth/cloud-platform"
+
 type GeminiDriver struct {
 	client *genai.Client
 }
@@ -23,15 +29,66 @@ func NewGeminiDriver(ctx context.Context, apiKey string) (*GeminiDriver, error)
 	return &GeminiDriver{client: client}, nil
 }
 
+// NewVertexAIGeminiDriver creates a Gemini driver that uses the Vertex AI
+// backend of the given Google Cloud project and location. It authenticates
+// with the service account in credentialsFile, or with application default
+// credentials when credentialsFile is empty.
+func NewVertexAIGeminiDriver(ctx context.Context, project, location, credentialsFile string) (*GeminiDriver, error) {
+	var missing []string
+	if project == "" {
+		missing = append(missing, "project")
+	}
+	if location == "" {
+		missing = append(missing, "location")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the Vertex AI backend requires a %s", strings.Join(missing, " and a "))
+	}
+
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes:          []string{vertexAIScope},
+		CredentialsFile: credentialsFile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Vertex AI credentials: %w", err)
+	}
+
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		Backend:     genai.BackendVertexAI,
+		Project:     project,
+		Location:    location,
+		Credentials: creds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini SDK client: %w", err)
+	}
+
+	return &GeminiDriver{client: client}, nil
+}
+
 func (d *GeminiDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
 	sdkConfig := &genai.GenerateContentConfig{
 		Temperature:      &req.Temperature,
 		MaxOutputTokens:  int32(req.MaxTokens),
 		ResponseMIMEType: req.ResponseMIMEType,
+		ResponseSchema:   toGeminiSchema(req.ResponseSchema),
+	}
+
+	if req.System != "" {
+		sdkConfig.SystemInstruction = genai.NewContentFromText(req.System, genai.RoleUser)
 	}
 
 	resp, err := d.client.Models.GenerateContent(ctx, req.Model, genai.Text(req.Prompt), sdkConfig)
 	if err != nil {
+		var sdkErr genai.APIError
+		if errors.As(err, &sdkErr) {
+			err = &APIError{
+				API:        "Gemini",
+				StatusCode: sdkErr.Code,
+				Type:       sdkErr.Status,
+				Message:    sdkErr.Message,
+			}
+		}
 		return nil, fmt.Errorf("failed to generate content from Gemini API: %w", err)
 	}
 
@@ -39,3 +96,26 @@ func (d *GeminiDriver) Generate(ctx context.Context, req GenerateRequest) (*Gene
 		Content: resp.Text(),
 	}, nil
 }
+
+func toGeminiSchema(schema *Schema) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+
+	converted := &genai.Schema{
+		Type:        genai.Type(strings.ToUpper(string(schema.Type))),
+		Description: schema.Description,
+		Enum:        schema.Enum,
+		Required:    schema.Required,
+		Items:       toGeminiSchema(schema.Items),
+	}
+
+	if len(schema.Properties) > 0 {
+		converted.Properties = make(map[string]*genai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			converted.Properties[name] = toGeminiSchema(property)
+		}
+	}
+
+	return converted
+}
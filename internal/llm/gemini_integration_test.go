@@ -57,6 +57,31 @@ func TestGeminiDriver_Generate(t *testing.T) {
 		assert.NotEmpty(t, res.Content)
 	})
 
+	t.Run("Success_GenerateStructuredContent", func(t *testing.T) {
+		driver, err := llm.NewGeminiDriver(ctx, apiKey)
+		require.NoError(t, err)
+
+		req := llm.GenerateRequest{
+			Model:            "gemini-2.5-flash",
+			Prompt:           "Reply with status OK",
+			Temperature:      0.1,
+			MaxTokens:        256,
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"status"},
+				Properties: map[string]*llm.Schema{
+					"status": {Type: llm.TypeString, Enum: []string{"OK", "FAIL"}},
+				},
+			},
+		}
+
+		res, err := driver.Generate(ctx, req)
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"status": "OK"}`, res.Content)
+	})
+
 	t.Run("Failure_InvalidAPIKey", func(t *testing.T) {
 		driver, err := llm.NewGeminiDriver(ctx, "invalid-api-key")
 		require.NoError(t, err)
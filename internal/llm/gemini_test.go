@@ -0,0 +1,74 @@
+package llm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiDriver_NewVertexAIGeminiDriver(t *testing.T) {
+	ctx := context.Background()
+
+	writeCredentials := func(t *testing.T) string {
+		t.Helper()
+
+		path := filepath.Join(t.TempDir(), "credentials.json")
+		err := os.WriteFile(path, []byte(`{
+			"type": "service_account",
+			"project_id": "my-project",
+			"private_key_id": "key-id",
+			"private_key": "not-a-real-key",
+			"client_email": "reviewer@my-project.iam.gserviceaccount.com",
+			"client_id": "1234567890",
+			"token_uri": "https://oauth2.googleapis.com/token"
+		}`), 0600)
+		require.NoError(t, err)
+
+		return path
+	}
+
+	t.Run("Success_InitDriverWithCredentialsFile", func(t *testing.T) {
+		driver, err := llm.NewVertexAIGeminiDriver(ctx, "my-project", "us-central1", writeCredentials(t))
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Success_InitDriverWithDefaultCredentials", func(t *testing.T) {
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeCredentials(t))
+
+		driver, err := llm.NewVertexAIGeminiDriver(ctx, "my-project", "us-central1", "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingProjectAndLocation", func(t *testing.T) {
+		driver, err := llm.NewVertexAIGeminiDriver(ctx, "", "", writeCredentials(t))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the Vertex AI backend requires a project and a location")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_MissingLocation", func(t *testing.T) {
+		driver, err := llm.NewVertexAIGeminiDriver(ctx, "my-project", "", writeCredentials(t))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the Vertex AI backend requires a location")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_MissingCredentialsFile", func(t *testing.T) {
+		driver, err := llm.NewVertexAIGeminiDriver(ctx, "my-project", "us-central1", filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to load Vertex AI credentials")
+		assert.Nil(t, driver)
+	})
+}
@@ -6,4 +6,8 @@ import (
 
 type Client interface {
 	GenerateContent(ctx context.Context, prompt string) (string, error)
+	GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error)
+	// Models returns the models that produced the responses so far, in the
+	// order they were first used.
+	Models() []string
 }
@@ -0,0 +1,69 @@
+package llm
+
+import "strings"
+
+// DefaultContextWindow is the context window, in tokens, assumed for models
+// that are not listed in contextWindows.
+const DefaultContextWindow = 32_768
+
+// contextWindows lists the context window, in tokens, of known model families.
+// Entries are matched by model name prefix, so more specific prefixes must come
+// first.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gemini-1.0-pro", 32_760},
+	{"gemini-1.5-pro", 2_097_152},
+	{"gemini-", 1_048_576},
+	{"claude-", 200_000},
+	{"gpt-4.1", 1_047_576},
+	{"gpt-4o", 128_000},
+	{"gpt-4-turbo", 128_000},
+	{"gpt-5", 400_000},
+	{"o1", 200_000},
+	{"o3", 200_000},
+	{"o4-mini", 200_000},
+}
+
+// ContextWindow returns the context window, in tokens, of the given model.
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, entry := range contextWindows {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return DefaultContextWindow
+}
+
+// reasoningModels lists the prefixes of the OpenAI reasoning model families,
+// which reject temperature and max_tokens. gpt-5-chat is the non-reasoning
+// variant of GPT-5.
+var reasoningModels = []struct {
+	prefix    string
+	reasoning bool
+}{
+	{"gpt-5-chat", false},
+	{"gpt-5", true},
+	{"o1", true},
+	{"o3", true},
+	{"o4-mini", true},
+}
+
+// isReasoningModel reports whether model is an OpenAI reasoning model.
+func isReasoningModel(model string) bool {
+	model = strings.ToLower(model)
+	for _, entry := range reasoningModels {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.reasoning
+		}
+	}
+	return false
+}
+
+// EstimateTokens returns a rough estimate of the number of tokens in text,
+// assuming about four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
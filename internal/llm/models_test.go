@@ -0,0 +1,27 @@
+package llm_test
+
+import (
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModels_ContextWindow(t *testing.T) {
+	t.Run("Success_KnownModels", func(t *testing.T) {
+		assert.Equal(t, 1_048_576, llm.ContextWindow("gemini-2.5-flash"))
+		assert.Equal(t, 2_097_152, llm.ContextWindow("gemini-1.5-pro-002"))
+		assert.Equal(t, 32_760, llm.ContextWindow("Gemini-1.0-Pro"))
+		assert.Equal(t, 128_000, llm.ContextWindow("gpt-4o-mini"))
+	})
+
+	t.Run("Success_UnknownModel", func(t *testing.T) {
+		assert.Equal(t, llm.DefaultContextWindow, llm.ContextWindow("my-custom-model"))
+	})
+}
+
+func TestModels_EstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, llm.EstimateTokens(""))
+	assert.Equal(t, 1, llm.EstimateTokens("abc"))
+	assert.Equal(t, 3, llm.EstimateTokens("hello world"))
+}
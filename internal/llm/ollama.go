@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/retry"
+)
+
+// DefaultOllamaHost is the address an Ollama server listens on by default.
+const DefaultOllamaHost = "http://localhost:11434"
+
+type OllamaDriver struct {
+	httpClient *http.Client
+	host       string
+}
+
+// NewOllamaDriver creates a driver for the Ollama chat API served at host, or
+// at the default local address when host is empty. Ollama needs no API key.
+func NewOllamaDriver(httpClient *http.Client, host string) (*OllamaDriver, error) {
+	if host == "" {
+		host = DefaultOllamaHost
+	}
+
+	return &OllamaDriver{
+		httpClient: httpClient,
+		host:       strings.TrimSuffix(host, "/"),
+	}, nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   any             `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaChatChunk is one line of a streamed chat response. The last line has
+// Done set; a line with Error set aborts the stream.
+type ollamaChatChunk struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error"`
+}
+
+func (d *OllamaDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	messages := []ollamaMessage{{Role: "user", Content: req.Prompt}}
+	if req.System != "" {
+		messages = append([]ollamaMessage{{Role: "system", Content: req.System}}, messages...)
+	}
+
+	body, err := json.Marshal(ollamaChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   true,
+		Format:   toOllamaFormat(req),
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Ollama chat request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, d.host+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := d.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama chat API: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := &APIError{
+			API:        "Ollama chat",
+			StatusCode: res.StatusCode,
+			RetryAfter: retry.ParseRetryAfter(res.Header),
+		}
+
+		var errResp ollamaChatChunk
+		if resBody, err := io.ReadAll(res.Body); err == nil && json.Unmarshal(resBody, &errResp) == nil {
+			apiErr.Message = errResp.Error
+		}
+
+		return nil, apiErr
+	}
+
+	return readOllamaStream(res.Body)
+}
+
+// readOllamaStream concatenates the message content of a streamed chat
+// response, which is sent as newline-delimited JSON objects.
+func readOllamaStream(r io.Reader) (*GenerateResponse, error) {
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode Ollama chat response: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("received an error from the Ollama chat API: %s", chunk.Error)
+		}
+
+		content.WriteString(chunk.Message.Content)
+
+		if chunk.Done {
+			return &GenerateResponse{Content: content.String()}, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Ollama chat response: %w", err)
+	}
+
+	return nil, fmt.Errorf("the Ollama chat response ended before it was done")
+}
+
+// toOllamaFormat constrains the response to the schema, or to any JSON value
+// when JSON is requested without a schema.
+func toOllamaFormat(req GenerateRequest) any {
+	switch {
+	case req.ResponseSchema != nil:
+		return req.ResponseSchema
+	case req.ResponseMIMEType == "application/json":
+		return "json"
+	default:
+		return nil
+	}
+}
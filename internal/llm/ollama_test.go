@@ -0,0 +1,196 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaDriver_NewOllamaDriver(t *testing.T) {
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := llm.NewOllamaDriver(http.DefaultClient, "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+}
+
+func TestOllamaDriver_Generate(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(t *testing.T, handler func(t *testing.T, body map[string]any, w http.ResponseWriter)) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/chat", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			handler(t, body, w)
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	t.Run("Success_GenerateStreamedContent", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, "qwen2.5-coder:14b", body["model"])
+			assert.Equal(t, true, body["stream"])
+			assert.Equal(t, []any{
+				map[string]any{"role": "system", "content": "You are a reviewer."},
+				map[string]any{"role": "user", "content": "Say 'OK'"},
+			}, body["messages"])
+			assert.NotContains(t, body, "format")
+
+			options := body["options"].(map[string]any)
+			assert.InDelta(t, 0.2, options["temperature"], 0.001)
+			assert.Equal(t, float64(128), options["num_predict"])
+
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "O"}, "done": false}`)
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "K"}, "done": false}`)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": ""}, "done": true, "done_reason": "stop"}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL+"/")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "qwen2.5-coder:14b",
+			System:           "You are a reviewer.",
+			Prompt:           "Say 'OK'",
+			Temperature:      0.2,
+			MaxTokens:        128,
+			ResponseMIMEType: "text/plain",
+		})
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "OK", res.Content)
+	})
+
+	t.Run("Success_GenerateWithResponseSchema", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, map[string]any{
+				"type":       "object",
+				"required":   []any{"status"},
+				"properties": map[string]any{"status": map[string]any{"type": "string"}},
+			}, body["format"])
+
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "{\"status\": \"OK\"}"}, "done": true}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "qwen2.5-coder:14b",
+			Prompt:           "Reply with status OK",
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &llm.Schema{
+				Type:       llm.TypeObject,
+				Required:   []string{"status"},
+				Properties: map[string]*llm.Schema{"status": {Type: llm.TypeString}},
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"status": "OK"}`, res.Content)
+	})
+
+	t.Run("Success_GenerateJSONFormat", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, "json", body["format"])
+
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "{}"}, "done": true}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "qwen2.5-coder:14b",
+			Prompt:           "Reply with an empty object",
+			ResponseMIMEType: "application/json",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", res.Content)
+	})
+
+	t.Run("Failure_ModelNotFound", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": "model \"missing\" not found, try pulling it first"}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "missing", Prompt: "Say 'OK'"})
+
+		var apiErr *llm.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Contains(t, err.Error(), `Ollama chat API returned status 404: model "missing" not found`)
+		assert.False(t, apiErr.Retryable())
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_ErrorInStream", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "O"}, "done": false}`)
+			fmt.Fprintln(w, `{"error": "out of memory"}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "qwen2.5-coder:14b", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "received an error from the Ollama chat API: out of memory")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_StreamEndedEarly", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprintln(w, `{"message": {"role": "assistant", "content": "O"}, "done": false}`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "qwen2.5-coder:14b", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the Ollama chat response ended before it was done")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_MalformedResponse", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprintln(w, `not json`)
+		})
+
+		driver, err := llm.NewOllamaDriver(server.Client(), server.URL)
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "qwen2.5-coder:14b", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode Ollama chat response")
+		assert.Nil(t, res)
+	})
+}
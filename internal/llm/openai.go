@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/retry"
+)
+
+// DefaultOpenAIBaseURL is the base URL of the OpenAI API. Other servers that
+// speak the chat completions protocol, such as vLLM, LM Studio or LiteLLM, are
+// used by pointing the driver at their base URL instead.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+type OpenAIDriver struct {
+	httpClient   *http.Client
+	baseURL      string
+	apiKey       string
+	organization string
+}
+
+// NewOpenAIDriver creates a driver for the chat completions API at baseURL,
+// or at the OpenAI API when baseURL is empty. The API key is only required by
+// the OpenAI API itself, since self-hosted servers often run without one.
+func NewOpenAIDriver(httpClient *http.Client, apiKey, baseURL, organization string) (*OpenAIDriver, error) {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+
+	if apiKey == "" && baseURL == DefaultOpenAIBaseURL {
+		return nil, fmt.Errorf("api key is required for the OpenAI API")
+	}
+
+	return &OpenAIDriver{
+		httpClient:   httpClient,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:       apiKey,
+		organization: organization,
+	}, nil
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIJSONSchema struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIChatRequest limits the response with MaxTokens, or with
+// MaxCompletionTokens for reasoning models, which also leave Temperature unset
+// since they only accept its default.
+type openAIChatRequest struct {
+	Model               string                `json:"model"`
+	Messages            []openAIMessage       `json:"messages"`
+	Temperature         *float32              `json:"temperature,omitempty"`
+	MaxTokens           int                   `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                   `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (d *OpenAIDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	messages := []openAIMessage{{Role: "user", Content: req.Prompt}}
+	if req.System != "" {
+		messages = append([]openAIMessage{{Role: "system", Content: req.System}}, messages...)
+	}
+
+	chatReq := openAIChatRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		ResponseFormat: toOpenAIResponseFormat(req),
+	}
+	if isReasoningModel(req.Model) {
+		chatReq.MaxCompletionTokens = req.MaxTokens
+	} else {
+		chatReq.Temperature = &req.Temperature
+		chatReq.MaxTokens = req.MaxTokens
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat completion request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if d.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+	if d.organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", d.organization)
+	}
+
+	res, err := d.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call chat completions API: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat completion response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := &APIError{
+			API:        "chat completions",
+			StatusCode: res.StatusCode,
+			RetryAfter: retry.ParseRetryAfter(res.Header),
+		}
+
+		var errResp openAIErrorResponse
+		if json.Unmarshal(resBody, &errResp) == nil {
+			apiErr.Type = errResp.Error.Type
+			apiErr.Message = errResp.Error.Message
+		}
+
+		return nil, apiErr
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(resBody, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode chat completion response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion response has no choices")
+	}
+
+	return &GenerateResponse{
+		Content: chatResp.Choices[0].Message.Content,
+	}, nil
+}
+
+// toOpenAIResponseFormat asks for a response constrained by the schema, or
+// for any JSON object when JSON is requested without a schema.
+func toOpenAIResponseFormat(req GenerateRequest) *openAIResponseFormat {
+	switch {
+	case req.ResponseSchema != nil:
+		return &openAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &openAIJSONSchema{
+				Name:   "response",
+				Schema: req.ResponseSchema,
+			},
+		}
+	case req.ResponseMIMEType == "application/json":
+		return &openAIResponseFormat{Type: "json_object"}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,257 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIDriver_NewOpenAIDriver(t *testing.T) {
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := llm.NewOpenAIDriver(http.DefaultClient, "fake-api-key", "", "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Success_CompatibleServerWithoutAPIKey", func(t *testing.T) {
+		driver, err := llm.NewOpenAIDriver(http.DefaultClient, "", "http://localhost:8000/v1", "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingAPIKey", func(t *testing.T) {
+		driver, err := llm.NewOpenAIDriver(http.DefaultClient, "", "", "")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "api key is required for the OpenAI API")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestOpenAIDriver_Generate(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(t *testing.T, handler func(t *testing.T, body map[string]any, w http.ResponseWriter)) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			handler(t, body, w)
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	t.Run("Success_GenerateContent", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer fake-api-key", r.Header.Get("Authorization"))
+			assert.Equal(t, "org-123", r.Header.Get("OpenAI-Organization"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+			assert.Equal(t, "gpt-4o", body["model"])
+			assert.Equal(t, []any{map[string]any{"role": "user", "content": "Say 'OK'"}}, body["messages"])
+			assert.InDelta(t, 0.2, body["temperature"], 0.001)
+			assert.Equal(t, float64(128), body["max_tokens"])
+			assert.NotContains(t, body, "response_format")
+
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "OK"}, "finish_reason": "stop"}]}`)
+		}))
+		defer server.Close()
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "fake-api-key", server.URL+"/v1/", "org-123")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "gpt-4o",
+			Prompt:           "Say 'OK'",
+			Temperature:      0.2,
+			MaxTokens:        128,
+			ResponseMIMEType: "text/plain",
+		})
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "OK", res.Content)
+	})
+
+	t.Run("Success_GenerateWithReasoningModel", func(t *testing.T) {
+		for _, model := range []string{"o1-mini", "o3", "gpt-5-mini"} {
+			server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+				assert.Equal(t, model, body["model"])
+				assert.Equal(t, float64(128), body["max_completion_tokens"])
+				assert.NotContains(t, body, "max_tokens")
+				assert.NotContains(t, body, "temperature")
+
+				fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "OK"}, "finish_reason": "stop"}]}`)
+			})
+
+			driver, err := llm.NewOpenAIDriver(server.Client(), "fake-api-key", server.URL+"/v1", "")
+			require.NoError(t, err)
+
+			res, err := driver.Generate(ctx, llm.GenerateRequest{
+				Model:       model,
+				Prompt:      "Say 'OK'",
+				Temperature: 0.2,
+				MaxTokens:   128,
+			})
+
+			assert.NoError(t, err)
+			require.NotNil(t, res)
+			assert.Equal(t, "OK", res.Content)
+		}
+	})
+
+	t.Run("Success_GenerateWithZeroTemperature", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, float64(0), body["temperature"])
+			assert.NotContains(t, body, "max_completion_tokens")
+
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "OK"}, "finish_reason": "stop"}]}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "fake-api-key", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		_, err = driver.Generate(ctx, llm.GenerateRequest{Model: "gpt-5-chat-latest", Prompt: "Say 'OK'"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Success_GenerateWithResponseSchema", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, map[string]any{
+				"type": "json_schema",
+				"json_schema": map[string]any{
+					"name": "response",
+					"schema": map[string]any{
+						"type":       "object",
+						"required":   []any{"status"},
+						"properties": map[string]any{"status": map[string]any{"type": "string", "enum": []any{"OK", "FAIL"}}},
+					},
+				},
+			}, body["response_format"])
+
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"status\": \"OK\"}"}}]}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "local-model",
+			Prompt:           "Reply with status OK",
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"status"},
+				Properties: map[string]*llm.Schema{
+					"status": {Type: llm.TypeString, Enum: []string{"OK", "FAIL"}},
+				},
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"status": "OK"}`, res.Content)
+	})
+
+	t.Run("Success_GenerateJSONObject", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			assert.Equal(t, map[string]any{"type": "json_object"}, body["response_format"])
+
+			fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{}"}}]}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{
+			Model:            "local-model",
+			Prompt:           "Reply with an empty object",
+			ResponseMIMEType: "application/json",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", res.Content)
+	})
+
+	t.Run("Failure_ErrorResponse", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error"}}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "invalid-api-key", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "gpt-4o", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "chat completions API returned status 401: Incorrect API key provided")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_NoChoices", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprint(w, `{"choices": []}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "gpt-4o", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "chat completion response has no choices")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_MalformedResponse", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprint(w, `not json`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		res, err := driver.Generate(ctx, llm.GenerateRequest{Model: "gpt-4o", Prompt: "Say 'OK'"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode chat completion response")
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_ContextCancelled", func(t *testing.T) {
+		server := newServer(t, func(t *testing.T, body map[string]any, w http.ResponseWriter) {
+			fmt.Fprint(w, `{"choices": []}`)
+		})
+
+		driver, err := llm.NewOpenAIDriver(server.Client(), "", server.URL+"/v1", "")
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		res, err := driver.Generate(cancelled, llm.GenerateRequest{Model: "gpt-4o", Prompt: "Say 'OK'"})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, res)
+	})
+}
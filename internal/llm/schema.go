@@ -0,0 +1,23 @@
+package llm
+
+type SchemaType string
+
+const (
+	TypeString  SchemaType = "string"
+	TypeInteger SchemaType = "integer"
+	TypeNumber  SchemaType = "number"
+	TypeBoolean SchemaType = "boolean"
+	TypeArray   SchemaType = "array"
+	TypeObject  SchemaType = "object"
+)
+
+// Schema is the provider-agnostic subset of JSON Schema used to constrain
+// structured model output. Drivers translate it into their own format.
+type Schema struct {
+	Type        SchemaType         `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
@@ -0,0 +1,31 @@
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutDriver bounds each request to a driver by a timeout, so that every
+// attempt of the retry loop and every entry of a fallback chain is given the
+// whole timeout.
+type TimeoutDriver struct {
+	driver  Driver
+	timeout time.Duration
+}
+
+// NewTimeoutDriver wraps driver so that each request is cancelled after
+// timeout. A timeout of zero or less returns driver unchanged.
+func NewTimeoutDriver(driver Driver, timeout time.Duration) Driver {
+	if timeout <= 0 {
+		return driver
+	}
+
+	return &TimeoutDriver{driver: driver, timeout: timeout}
+}
+
+func (d *TimeoutDriver) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.driver.Generate(ctx, req)
+}
@@ -0,0 +1,61 @@
+package llm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTimeoutDriver_Generate(t *testing.T) {
+	req := llm.GenerateRequest{Model: "gemini-2.5-pro", Prompt: "Say 'OK'"}
+
+	t.Run("Success_NoTimeout", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		assert.Same(t, mockDriver, llm.NewTimeoutDriver(mockDriver, 0))
+	})
+
+	t.Run("Failure_RequestTimesOut", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+		mockDriver.On("Generate", mock.Anything, req).
+			Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+			Return(nil, context.DeadlineExceeded)
+
+		driver := llm.NewTimeoutDriver(mockDriver, 10*time.Millisecond)
+
+		resp, err := driver.Generate(context.Background(), req)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, resp)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Success_RetryWithFreshDeadline", func(t *testing.T) {
+		cfg := config.LLM{
+			Model: "gemini-2.5-pro",
+			Retry: config.Retry{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		}
+
+		mockDriver := new(MockDriver)
+		mockDriver.On("Generate", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+			Return(nil, context.DeadlineExceeded).Once()
+		mockDriver.On("Generate", mock.MatchedBy(func(ctx context.Context) bool {
+			return ctx.Err() == nil
+		}), mock.Anything).
+			Return(&llm.GenerateResponse{Content: "OK"}, nil).Once()
+
+		client := llm.NewClient(llm.NewTimeoutDriver(mockDriver, 10*time.Millisecond), cfg)
+
+		content, err := client.GenerateContent(context.Background(), "Say 'OK'")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "OK", content)
+		mockDriver.AssertExpectations(t)
+	})
+}
@@ -24,6 +24,6 @@ func Setup(levelStr string) {
 		AddSource: level == slog.LevelDebug,
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, opts))
 	slog.SetDefault(logger)
 }
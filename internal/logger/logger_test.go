@@ -2,11 +2,16 @@ package logger_test
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"testing"
 
+	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLogger_Setup(t *testing.T) {
@@ -40,3 +45,34 @@ func TestLogger_Setup(t *testing.T) {
 		})
 	}
 }
+
+func TestLogger_SetupRedactsSecrets(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+	t.Cleanup(func() {
+		os.Stderr = stderr
+		logger.Setup("info")
+	})
+
+	logger.Setup("debug")
+
+	scm := config.SCM{Platform: config.PlatformGitHub, Token: "scm-s3cr3t"}
+	llm := config.LLM{Provider: config.ProviderGemini, APIKey: "llm-s3cr3t"}
+
+	slog.Debug("Secrets", "token", scm.Token, "api_key", llm.APIKey)
+	slog.Info("Config", "scm", scm, "llm", llm)
+	slog.Info("Group", slog.Group("llm", "api_key", llm.APIKey))
+	slog.Warn("Formatted", "scm", fmt.Sprintf("%+v", scm))
+
+	require.NoError(t, w.Close())
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(output), `"token":"[REDACTED]"`)
+	assert.Contains(t, string(output), `"api_key":"[REDACTED]"`)
+	assert.NotContains(t, string(output), "scm-s3cr3t")
+	assert.NotContains(t, string(output), "llm-s3cr3t")
+}
@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/config"
+)
+
+// Policy controls how often and how long to wait before retrying a failed
+// call.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below one mean a single attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for every
+	// further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay. A server asking to wait longer than
+	// MaxDelay is not retried.
+	MaxDelay time.Duration
+	// Jitter is the fraction, between 0 and 1, by which each backoff delay is
+	// randomly shortened so that concurrent callers do not retry in lockstep.
+	Jitter float64
+}
+
+func NewPolicy(cfg config.Retry) Policy {
+	return Policy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   cfg.BaseDelay,
+		MaxDelay:    cfg.MaxDelay,
+		Jitter:      cfg.Jitter,
+	}
+}
+
+// Classifier reports whether a call that failed with err may be retried and,
+// when the server said so, how long to wait before doing it.
+type Classifier func(err error) (retryable bool, after time.Duration)
+
+// Do calls fn until it succeeds, fails with an error that classify does not
+// consider retryable, or runs out of attempts, and returns the last error.
+// Each retry is logged with the operation name, the attempt number and the
+// delay.
+func Do(ctx context.Context, p Policy, op string, classify Classifier, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		retryable, after := classify(err)
+		if !retryable {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if after > 0 {
+			if p.MaxDelay > 0 && after > p.MaxDelay {
+				slog.Warn("Not retrying, server asked to wait longer than the maximum delay", "operation", op, "attempt", attempt, "retry_after", after, "max_delay", p.MaxDelay, "error", err)
+				return err
+			}
+			delay = after
+		}
+
+		slog.Warn("Retrying", "operation", op, "attempt", attempt+1, "max_attempts", p.MaxAttempts, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the retry that follows the given attempt.
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * min(p.Jitter, 1) * float64(delay))
+	}
+
+	return delay
+}
+
+// ParseRetryAfter parses a Retry-After header, given either in seconds or as
+// an HTTP date, and returns zero when it is missing or invalid.
+func ParseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,169 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func classify(after time.Duration) retry.Classifier {
+	return func(err error) (bool, time.Duration) {
+		return errors.Is(err, errTransient), after
+	}
+}
+
+func TestRetry_NewPolicy(t *testing.T) {
+	t.Run("Success_FromConfig", func(t *testing.T) {
+		policy := retry.NewPolicy(config.Retry{
+			MaxAttempts: 5,
+			BaseDelay:   time.Second,
+			MaxDelay:    time.Minute,
+			Jitter:      0.5,
+		})
+
+		assert.Equal(t, retry.Policy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}, policy)
+	})
+}
+
+func TestRetry_Do(t *testing.T) {
+	ctx := context.Background()
+	policy := retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Jitter: 0.5}
+
+	t.Run("Success_FirstAttempt", func(t *testing.T) {
+		calls := 0
+
+		err := retry.Do(ctx, policy, "test", classify(0), func() error {
+			calls++
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Success_RetryTransientErrors", func(t *testing.T) {
+		calls := 0
+
+		err := retry.Do(ctx, policy, "test", classify(0), func() error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Success_HonorRetryAfter", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+
+		err := retry.Do(ctx, policy, "test", classify(5*time.Millisecond), func() error {
+			calls++
+			if calls < 2 {
+				return errTransient
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+	})
+
+	t.Run("Success_SingleAttemptWithoutPolicy", func(t *testing.T) {
+		calls := 0
+
+		err := retry.Do(ctx, retry.Policy{}, "test", classify(0), func() error {
+			calls++
+			return errTransient
+		})
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Failure_NotRetryable", func(t *testing.T) {
+		calls := 0
+		permanent := errors.New("unauthorized")
+
+		err := retry.Do(ctx, policy, "test", classify(0), func() error {
+			calls++
+			return permanent
+		})
+
+		assert.ErrorIs(t, err, permanent)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Failure_OutOfAttempts", func(t *testing.T) {
+		calls := 0
+
+		err := retry.Do(ctx, policy, "test", classify(0), func() error {
+			calls++
+			return errTransient
+		})
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Failure_RetryAfterExceedsMaxDelay", func(t *testing.T) {
+		calls := 0
+
+		err := retry.Do(ctx, policy, "test", classify(time.Hour), func() error {
+			calls++
+			return errTransient
+		})
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Failure_ContextCancelledWhileWaiting", func(t *testing.T) {
+		calls := 0
+		cancelled, cancel := context.WithCancel(ctx)
+
+		err := retry.Do(cancelled, retry.Policy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}, "test", classify(0), func() error {
+			calls++
+			time.AfterFunc(time.Millisecond, cancel)
+			return errTransient
+		})
+
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetry_ParseRetryAfter(t *testing.T) {
+	t.Run("Success_Seconds", func(t *testing.T) {
+		header := http.Header{"Retry-After": []string{"30"}}
+
+		assert.Equal(t, 30*time.Second, retry.ParseRetryAfter(header))
+	})
+
+	t.Run("Success_HTTPDate", func(t *testing.T) {
+		header := http.Header{"Retry-After": []string{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}
+
+		after := retry.ParseRetryAfter(header)
+
+		assert.Greater(t, after, 55*time.Second)
+		assert.LessOrEqual(t, after, time.Minute)
+	})
+
+	t.Run("Success_MissingOrInvalid", func(t *testing.T) {
+		assert.Zero(t, retry.ParseRetryAfter(http.Header{}))
+		assert.Zero(t, retry.ParseRetryAfter(http.Header{"Retry-After": []string{"soon"}}))
+		assert.Zero(t, retry.ParseRetryAfter(http.Header{"Retry-After": []string{"-5"}}))
+	})
+}
@@ -0,0 +1,92 @@
+package reviewer
+
+import (
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/scm"
+)
+
+// AnchorFindings converts the findings that point at lines visible in the diff
+// into review comments. Findings that cannot be placed on the diff are
+// returned separately so they can be reported in the summary instead.
+func AnchorFindings(findings []Finding, files []diff.File) ([]*scm.ReviewComment, []Finding) {
+	filesByPath := make(map[string]*diff.File, len(files))
+	for i := range files {
+		filesByPath[files[i].Path()] = &files[i]
+	}
+
+	var comments []*scm.ReviewComment
+	var unanchored []Finding
+
+	for _, finding := range findings {
+		comment, ok := anchor(finding, filesByPath[finding.File])
+		if !ok {
+			unanchored = append(unanchored, finding)
+			continue
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, unanchored
+}
+
+func anchor(finding Finding, file *diff.File) (*scm.ReviewComment, bool) {
+	if file == nil || finding.StartLine <= 0 {
+		return nil, false
+	}
+
+	side := scm.SideRight
+	if strings.EqualFold(finding.Side, string(scm.SideLeft)) {
+		side = scm.SideLeft
+	}
+
+	endLine := finding.EndLine
+	if endLine < finding.StartLine {
+		endLine = finding.StartLine
+	}
+
+	// A comment, including every line of a multi-line range, must fall inside a
+	// single hunk for the platforms to accept it.
+	for _, hunk := range file.Hunks {
+		start, ok := findLine(hunk, side, finding.StartLine)
+		if !ok {
+			continue
+		}
+
+		end, ok := findLine(hunk, side, endLine)
+		if !ok {
+			continue
+		}
+
+		comment := &scm.ReviewComment{
+			Path:    file.Path(),
+			OldPath: file.OldPath,
+			Side:    side,
+			End:     end,
+			Body:    finding.Markdown(),
+		}
+		if endLine > finding.StartLine {
+			comment.Start = &start
+		}
+
+		return comment, true
+	}
+
+	return nil, false
+}
+
+func findLine(hunk diff.Hunk, side scm.DiffSide, number int) (scm.DiffLine, bool) {
+	for _, line := range hunk.Lines {
+		lineNumber := line.NewLine
+		if side == scm.SideLeft {
+			lineNumber = line.OldLine
+		}
+
+		if lineNumber == number {
+			return scm.DiffLine{OldLine: line.OldLine, NewLine: line.NewLine}, true
+		}
+	}
+
+	return scm.DiffLine{}, false
+}
@@ -0,0 +1,102 @@
+package reviewer_test
+
+import (
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchor_AnchorFindings(t *testing.T) {
+	rawDiff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-import "os"
++import "fmt"
++
+ func main() {
+ }
+@@ -20,2 +21,2 @@ func helper() {
+-	return 1
++	return 2
+ }
+diff --git a/removed.go b/removed.go
+deleted file mode 100644
+--- a/removed.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-var x = 1
+`
+	files := diff.Parse(rawDiff)
+
+	t.Run("Success_AnchorSingleLine", func(t *testing.T) {
+		comments, unanchored := reviewer.AnchorFindings([]reviewer.Finding{
+			{File: "main.go", StartLine: 2, EndLine: 2, Rationale: "Use fmt"},
+		}, files)
+
+		assert.Empty(t, unanchored)
+		require.Len(t, comments, 1)
+		assert.Equal(t, &scm.ReviewComment{
+			Path:    "main.go",
+			OldPath: "main.go",
+			Side:    scm.SideRight,
+			End:     scm.DiffLine{NewLine: 2},
+			Body:    "Use fmt",
+		}, comments[0])
+	})
+
+	t.Run("Success_AnchorMultiLineRange", func(t *testing.T) {
+		comments, unanchored := reviewer.AnchorFindings([]reviewer.Finding{
+			{File: "main.go", StartLine: 2, EndLine: 4, Title: "Title", Rationale: "Body"},
+		}, files)
+
+		assert.Empty(t, unanchored)
+		require.Len(t, comments, 1)
+		assert.Equal(t, &scm.ReviewComment{
+			Path:    "main.go",
+			OldPath: "main.go",
+			Side:    scm.SideRight,
+			Start:   &scm.DiffLine{NewLine: 2},
+			End:     scm.DiffLine{OldLine: 3, NewLine: 4},
+			Body:    "**Title**\n\nBody",
+		}, comments[0])
+	})
+
+	t.Run("Success_AnchorLeftSide", func(t *testing.T) {
+		comments, unanchored := reviewer.AnchorFindings([]reviewer.Finding{
+			{File: "removed.go", StartLine: 1, EndLine: 2, Side: "left", Rationale: "Why removed?"},
+		}, files)
+
+		assert.Empty(t, unanchored)
+		require.Len(t, comments, 1)
+		assert.Equal(t, &scm.ReviewComment{
+			Path:    "removed.go",
+			OldPath: "removed.go",
+			Side:    scm.SideLeft,
+			Start:   &scm.DiffLine{OldLine: 1},
+			End:     scm.DiffLine{OldLine: 2},
+			Body:    "Why removed?",
+		}, comments[0])
+	})
+
+	t.Run("Success_FallBackForUnanchorableFindings", func(t *testing.T) {
+		findings := []reviewer.Finding{
+			{File: "unknown.go", StartLine: 1, EndLine: 1},
+			{File: "main.go", StartLine: 10, EndLine: 10},
+			{File: "main.go", StartLine: 4, EndLine: 22},
+			{File: "main.go"},
+		}
+
+		comments, unanchored := reviewer.AnchorFindings(findings, files)
+
+		assert.Empty(t, comments)
+		assert.Equal(t, findings, unanchored)
+	})
+}
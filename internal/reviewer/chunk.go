@@ -0,0 +1,325 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/llm"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/fzl-22/elgtm/internal/tmpl"
+)
+
+// minChunkTokens is the smallest diff budget a chunk is given, even when the
+// prompt leaves less room than that in the context window.
+const minChunkTokens = 256
+
+const consolidationPrompt = `You are consolidating the code review of the pull request "{{ .Title }}". The diff was too large to review at once, so it was split into {{ len .Chunks }} parts that were reviewed independently.
+
+Merge the partial reviews below into a single review:
+* Write one overall summary in Markdown that covers the whole pull request, without referring to the parts.
+* Keep every distinct finding, merging findings that describe the same issue at the same location.
+* Do not add findings that are not supported by the partial reviews.
+* End with a fenced code block tagged ` + "`findings`" + ` containing the merged findings as a JSON array, using the same fields as the findings below.
+{{ range .Chunks }}
+# Part {{ .Number }}
+
+**Files**: {{ .Files }}
+
+**Summary**:
+{{ .Summary }}
+
+**Findings**:
+` + "```json" + `
+{{ .Findings }}
+` + "```" + `
+{{ end }}`
+
+type chunkReview struct {
+	Number   int
+	Files    string
+	Summary  string
+	Findings string
+
+	findings []Finding
+}
+
+// reviewPullRequest reviews the pull request with a single prompt when it fits
+// in the model's context window. Larger diffs are split into chunks that are
+// reviewed concurrently and then consolidated into a single review. Chunks that
+// fail are left out of the review, which then notes the files it misses. When
+// the consolidation fails, the reviews of the chunks are posted one after the
+// other.
+func (e *Engine) reviewPullRequest(ctx context.Context, promptContent string, pr scm.PullRequest) (string, []Finding, error) {
+	prompts, chunks, err := e.reviewPrompts(promptContent, pr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if chunks == nil {
+		return e.generateReview(ctx, prompts[0])
+	}
+
+	reviews := make([]chunkReview, len(chunks))
+	errs := runPool(ctx, len(chunks), e.cfg.LLM.Concurrency, func(ctx context.Context, i int) error {
+		slog.Info("Reviewing chunk", "chunk", i+1, "chunks", len(chunks), "files", len(chunks[i]), "length", len(prompts[i]))
+
+		summary, findings, err := e.generateReview(ctx, prompts[i])
+		if err != nil {
+			return err
+		}
+
+		reviews[i] = newChunkReview(i+1, chunks[i], summary, findings)
+		return nil
+	})
+
+	if err := ctx.Err(); err != nil {
+		return "", nil, fmt.Errorf("review cancelled: %w", err)
+	}
+
+	var reviewed []chunkReview
+	var failedFiles []string
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			slog.Warn("Failed to review chunk", "chunk", i+1, "chunks", len(chunks), "error", err)
+			failures = append(failures, fmt.Errorf("failed to review chunk %d of %d: %w", i+1, len(chunks), err))
+			failedFiles = appendPaths(failedFiles, chunks[i])
+			continue
+		}
+		reviewed = append(reviewed, reviews[i])
+	}
+
+	if len(reviewed) == 0 {
+		return "", nil, errors.Join(failures...)
+	}
+
+	summary, findings, err := e.consolidate(ctx, pr, reviewed)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", nil, fmt.Errorf("review cancelled: %w", ctxErr)
+		}
+		slog.Warn("Failed to consolidate chunk reviews, posting them separately", "chunks", len(reviewed), "error", err)
+		summary, findings = joinChunkReviews(reviewed)
+	}
+
+	if len(failedFiles) > 0 {
+		summary += "\n\n" + failedFilesNote(failedFiles)
+	}
+
+	return summary, findings, nil
+}
+
+// reviewPrompts renders the prompts that review the pull request: a single
+// prompt when it fits in the model's context window, and otherwise one prompt
+// per chunk of the diff, returned along with the files of each chunk.
+func (e *Engine) reviewPrompts(promptContent string, pr scm.PullRequest) ([]string, [][]scm.ChangedFile, error) {
+	prompt, err := e.generatePrompt(promptContent, pr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prompt generation failed: %w", err)
+	}
+
+	slog.Info("Prompt Generated", "length", len(prompt))
+
+	budget := e.promptBudget()
+	if llm.EstimateTokens(prompt) <= budget || len(pr.Files) == 0 {
+		return []string{prompt}, nil, nil
+	}
+
+	// Measure the prompt without any diff to learn how much room is left for it.
+	empty := pr
+	empty.RawDiff = ""
+	empty.Files = nil
+	emptyPrompt, err := e.generatePrompt(promptContent, empty)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prompt generation failed: %w", err)
+	}
+
+	chunks := SplitFiles(pr.Files, max(budget-llm.EstimateTokens(emptyPrompt), minChunkTokens))
+
+	slog.Info("Diff exceeds the context window, reviewing in chunks", "estimated_tokens", llm.EstimateTokens(prompt), "budget", budget, "chunks", len(chunks))
+
+	prompts := make([]string, len(chunks))
+	for i, files := range chunks {
+		chunk := pr
+		chunk.Files = files
+		chunk.RawDiff = diff.Format(files)
+
+		prompts[i], err = e.generatePrompt(promptContent, chunk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("prompt generation failed: %w", err)
+		}
+	}
+
+	return prompts, chunks, nil
+}
+
+// failedFilesNote tells readers of a partial review which files it does not
+// cover.
+func failedFilesNote(paths []string) string {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, "`"+path+"`")
+	}
+	return "> [!WARNING]\n> This review is incomplete: the following files could not be reviewed: " + strings.Join(quoted, ", ") + "."
+}
+
+// joinChunkReviews returns the summaries of the reviews, one section per
+// chunk under a note that they could not be consolidated, and all their
+// findings.
+func joinChunkReviews(reviews []chunkReview) (string, []Finding) {
+	var b strings.Builder
+	var findings []Finding
+
+	b.WriteString("> [!NOTE]\n> The diff was reviewed in parts whose reviews could not be consolidated, so each part is summarized separately.")
+	for _, review := range reviews {
+		fmt.Fprintf(&b, "\n\n### Part %d\n\n**Files**: %s\n\n%s", review.Number, review.Files, review.Summary)
+		findings = append(findings, review.findings...)
+	}
+
+	return b.String(), findings
+}
+
+// appendPaths appends the paths of the files that are not in paths yet.
+func appendPaths(paths []string, files []scm.ChangedFile) []string {
+	for i := range files {
+		if !slices.Contains(paths, files[i].Path()) {
+			paths = append(paths, files[i].Path())
+		}
+	}
+	return paths
+}
+
+// consolidate merges the reviews of the chunks into a single review,
+// deduplicating their findings. A single review needs no consolidation.
+func (e *Engine) consolidate(ctx context.Context, pr scm.PullRequest, reviews []chunkReview) (string, []Finding, error) {
+	if len(reviews) == 1 {
+		return reviews[0].Summary, reviews[0].findings, nil
+	}
+
+	t := struct {
+		Title  string
+		Chunks []chunkReview
+	}{
+		Title:  pr.Title,
+		Chunks: reviews,
+	}
+
+	prompt, err := tmpl.Generate("consolidation", consolidationPrompt, t)
+	if err != nil {
+		return "", nil, fmt.Errorf("consolidation prompt generation failed: %w", err)
+	}
+
+	slog.Info("Consolidating chunk reviews", "chunks", len(reviews), "length", len(prompt))
+
+	summary, findings, err := e.generateReview(ctx, prompt)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to consolidate review: %w", err)
+	}
+
+	return summary, findings, nil
+}
+
+// promptBudget returns the number of tokens a single prompt may use: the
+// context window of the smallest model that may serve it minus the room
+// reserved for the response, with a margin for the rough token estimate.
+func (e *Engine) promptBudget() int {
+	window := e.cfg.LLM.ContextWindow
+	if window <= 0 {
+		window = llm.ContextWindow(e.cfg.LLM.Model)
+	}
+
+	// Invalid fallbacks are reported when the driver is created.
+	fallbacks, _ := e.cfg.LLM.FallbackConfigs()
+	for _, fallback := range fallbacks {
+		window = min(window, llm.ContextWindow(fallback.Model))
+	}
+
+	return (window - e.cfg.LLM.MaxTokens) * 9 / 10
+}
+
+func newChunkReview(number int, files []scm.ChangedFile, summary string, findings []Finding) chunkReview {
+	paths := make([]string, 0, len(files))
+	for i := range files {
+		paths = append(paths, files[i].Path())
+	}
+
+	if findings == nil {
+		findings = []Finding{}
+	}
+	findingsJSON, _ := json.MarshalIndent(findings, "", "  ")
+
+	return chunkReview{
+		Number:   number,
+		Files:    strings.Join(paths, ", "),
+		Summary:  summary,
+		Findings: string(findingsJSON),
+		findings: findings,
+	}
+}
+
+// SplitFiles groups the files into chunks whose diff is estimated to fit in
+// budget tokens. Files are kept whole when they fit and otherwise split along
+// hunk boundaries; a single hunk larger than the budget gets a chunk of its
+// own.
+func SplitFiles(files []scm.ChangedFile, budget int) [][]scm.ChangedFile {
+	var chunks [][]scm.ChangedFile
+	var current []scm.ChangedFile
+	used := 0
+
+	for _, file := range files {
+		for _, part := range splitFile(file, budget) {
+			tokens := estimateFileTokens(part)
+			if len(current) > 0 && used+tokens > budget {
+				chunks = append(chunks, current)
+				current, used = nil, 0
+			}
+			current = append(current, part)
+			used += tokens
+		}
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// splitFile splits a file whose diff does not fit in budget tokens into parts
+// made of consecutive hunks.
+func splitFile(file scm.ChangedFile, budget int) []scm.ChangedFile {
+	if len(file.Hunks) <= 1 || estimateFileTokens(file) <= budget {
+		return []scm.ChangedFile{file}
+	}
+
+	var parts []scm.ChangedFile
+	part := file
+	part.Hunks = nil
+	used := 0
+
+	for _, hunk := range file.Hunks {
+		single := file
+		single.Hunks = []diff.Hunk{hunk}
+		tokens := estimateFileTokens(single)
+
+		if len(part.Hunks) > 0 && used+tokens > budget {
+			parts = append(parts, part)
+			part.Hunks = nil
+			used = 0
+		}
+		part.Hunks = append(part.Hunks, hunk)
+		used += tokens
+	}
+
+	return append(parts, part)
+}
+
+func estimateFileTokens(file scm.ChangedFile) int {
+	return llm.EstimateTokens(diff.Format([]scm.ChangedFile{file}))
+}
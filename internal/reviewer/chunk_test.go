@@ -0,0 +1,287 @@
+package reviewer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fileDiff returns the diff of a file adding the given number of hunks, each
+// made of ten long added lines.
+func fileDiff(path string, hunks int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	for h := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,0 +%d,10 @@\n", h*100, h*100+1)
+		for range 10 {
+			fmt.Fprintf(&b, "+%s\n", strings.Repeat("x", 96))
+		}
+	}
+	return b.String()
+}
+
+func TestChunk_SplitFiles(t *testing.T) {
+	t.Run("Success_SingleChunkWhenEverythingFits", func(t *testing.T) {
+		files := diff.Parse(fileDiff("a.go", 1) + fileDiff("b.go", 1))
+
+		chunks := reviewer.SplitFiles(files, 10000)
+
+		require.Len(t, chunks, 1)
+		assert.Len(t, chunks[0], 2)
+	})
+
+	t.Run("Success_PackWholeFiles", func(t *testing.T) {
+		// Each file is about 260 tokens.
+		files := diff.Parse(fileDiff("a.go", 1) + fileDiff("b.go", 1) + fileDiff("c.go", 1))
+
+		chunks := reviewer.SplitFiles(files, 600)
+
+		require.Len(t, chunks, 2)
+		require.Len(t, chunks[0], 2)
+		assert.Equal(t, "a.go", chunks[0][0].Path())
+		assert.Equal(t, "b.go", chunks[0][1].Path())
+		require.Len(t, chunks[1], 1)
+		assert.Equal(t, "c.go", chunks[1][0].Path())
+	})
+
+	t.Run("Success_SplitLargeFileAlongHunks", func(t *testing.T) {
+		files := diff.Parse(fileDiff("big.go", 5))
+
+		chunks := reviewer.SplitFiles(files, 600)
+
+		require.Len(t, chunks, 3)
+		var hunks []int
+		for _, chunk := range chunks {
+			require.Len(t, chunk, 1)
+			assert.Equal(t, "big.go", chunk[0].Path())
+			for _, hunk := range chunk[0].Hunks {
+				hunks = append(hunks, hunk.NewStart)
+			}
+		}
+		assert.Equal(t, []int{1, 101, 201, 301, 401}, hunks)
+	})
+
+	t.Run("Success_OversizedHunkGetsOwnChunk", func(t *testing.T) {
+		files := diff.Parse(fileDiff("a.go", 1) + fileDiff("b.go", 1))
+
+		chunks := reviewer.SplitFiles(files, 10)
+
+		require.Len(t, chunks, 2)
+	})
+
+	t.Run("Success_NoFiles", func(t *testing.T) {
+		assert.Empty(t, reviewer.SplitFiles(nil, 1000))
+	})
+}
+
+func TestChunk_Run(t *testing.T) {
+	rawDiff := fileDiff("a.go", 1) + fileDiff("b.go", 1) + fileDiff("c.go", 1)
+
+	newEngine := func(t *testing.T, opts ...func(*config.Config)) (*reviewer.Engine, *MockSCMClient, *MockLLMClient) {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(tempDir, "general.md"), []byte("Review {{ .Title }}:\n{{ .RawDiff }}"), 0644)
+		require.NoError(t, err)
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			LLM: config.LLM{
+				MaxTokens:     100,
+				ContextWindow: 700,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+			},
+		}
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, "owner", "repo", 123).
+			Return(&scm.PullRequest{
+				Number:  123,
+				Title:   "Big change",
+				HeadSHA: "abc123",
+				RawDiff: rawDiff,
+				Files:   diff.Parse(rawDiff),
+			}, nil)
+
+		return reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient), mockSCMClient, mockLLMClient
+	}
+
+	isChunkPrompt := func(files ...string) func(string) bool {
+		return func(prompt string) bool {
+			if !strings.HasPrefix(prompt, "Review Big change:") {
+				return false
+			}
+			for _, path := range []string{"a.go", "b.go", "c.go"} {
+				want := false
+				for _, file := range files {
+					want = want || file == path
+				}
+				if strings.Contains(prompt, "b/"+path) != want {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	t.Run("Success_ReviewChunksAndConsolidate", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("a.go", "b.go"))).
+			Return("First part.\n\n```findings\n[{\"file\":\"a.go\",\"start_line\":1,\"end_line\":1,\"title\":\"Long line\",\"rationale\":\"Wrap it.\"}]\n```", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("c.go"))).
+			Return("Second part.", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.HasPrefix(prompt, `You are consolidating the code review of the pull request "Big change"`) &&
+				strings.Contains(prompt, "# Part 1\n\n**Files**: a.go, b.go\n\n**Summary**:\nFirst part.") &&
+				strings.Contains(prompt, `"title": "Long line"`) &&
+				strings.Contains(prompt, "# Part 2\n\n**Files**: c.go\n\n**Summary**:\nSecond part.")
+		})).Return("Whole review.\n\n```findings\n[{\"file\":\"a.go\",\"start_line\":1,\"end_line\":1,\"title\":\"Long line\",\"rationale\":\"Wrap it.\"}]\n```", nil).Once()
+
+		mockSCMClient.On("PostReview", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(r *scm.Review) bool {
+			return len(r.Comments) == 1 && r.Comments[0].Path == "a.go"
+		})).Return(nil)
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\nWhole review."
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_ReviewChunksConcurrently", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, func(cfg *config.Config) {
+			cfg.LLM.Concurrency = 2
+		})
+
+		// Each chunk waits for the other to start, which only completes when
+		// both run at the same time.
+		var started sync.WaitGroup
+		started.Add(2)
+		waitForBoth := func(mock.Arguments) {
+			started.Done()
+			started.Wait()
+		}
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("a.go", "b.go"))).
+			Run(waitForBoth).Return("First part.", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("c.go"))).
+			Run(waitForBoth).Return("Second part.", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.HasPrefix(prompt, "You are consolidating")
+		})).Return("Whole review.", nil).Once()
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.Anything).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_PostPartialReviewWhenChunkFails", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("a.go", "b.go"))).
+			Return("", fmt.Errorf("boom")).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("c.go"))).
+			Return("Second part.", nil).Once()
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\nSecond part.\n\n"+
+				"> [!WARNING]\n> This review is incomplete: the following files could not be reviewed: `a.go`, `b.go`."
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Failure_AllChunksFailed", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return("", fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to review chunk 1 of 2")
+		assert.Contains(t, err.Error(), "failed to review chunk 2 of 2")
+		mockSCMClient.AssertNotCalled(t, "PostIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure_ParentContextCancelled", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("a.go", "b.go"))).
+			Run(func(mock.Arguments) { cancel() }).
+			Return("", context.Canceled).Once()
+
+		err := engine.Run(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "review cancelled")
+		mockLLMClient.AssertNumberOfCalls(t, "GenerateContent", 1)
+		mockSCMClient.AssertNotCalled(t, "PostIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success_PostChunkReviewsWhenConsolidationFails", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("a.go", "b.go"))).
+			Return("First part.\n\n```findings\n[{\"file\":\"a.go\",\"start_line\":1,\"end_line\":1,\"title\":\"Long line\",\"rationale\":\"Wrap it.\"}]\n```", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(isChunkPrompt("c.go"))).
+			Return("Second part.", nil).Once()
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return("", fmt.Errorf("boom")).Once()
+
+		mockSCMClient.On("PostReview", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(r *scm.Review) bool {
+			return len(r.Comments) == 1 && r.Comments[0].Path == "a.go"
+		})).Return(nil)
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\n"+
+				"> [!NOTE]\n> The diff was reviewed in parts whose reviews could not be consolidated, so each part is summarized separately.\n\n"+
+				"### Part 1\n\n**Files**: a.go, b.go\n\nFirst part.\n\n"+
+				"### Part 2\n\n**Files**: c.go\n\nSecond part."
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,82 @@
+package reviewer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/scm"
+)
+
+// renderPrompts renders the prompts the review would send to the model, one
+// section per chunk when the diff does not fit in the context window.
+func (e *Engine) renderPrompts(promptContent string, pr scm.PullRequest) (string, error) {
+	prompts, chunks, err := e.reviewPrompts(promptContent, pr)
+	if err != nil {
+		return "", err
+	}
+
+	if e.cfg.Review.OutputMode == config.OutputModeStructured {
+		for i := range prompts {
+			prompts[i] += structuredOutputInstructions
+		}
+	}
+
+	if chunks == nil {
+		return prompts[0] + "\n", nil
+	}
+
+	var b strings.Builder
+	for i, prompt := range prompts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "<!-- elgtm:prompt chunk=%d/%d files=%d -->\n%s\n", i+1, len(prompts), len(chunks[i]), prompt)
+	}
+	return b.String(), nil
+}
+
+// renderDryRun renders the summary comment exactly as it would be posted,
+// followed by the inline comments of the review.
+func (e *Engine) renderDryRun(body string, pr *scm.PullRequest, comments []*scm.ReviewComment) string {
+	var b strings.Builder
+	b.WriteString(e.marker(pr).String() + "\n" + body + "\n")
+
+	if len(comments) > 0 {
+		fmt.Fprintf(&b, "\n# Inline Comments (%d)\n", len(comments))
+	}
+
+	for _, comment := range comments {
+		location := fmt.Sprintf("%s:%d", comment.Path, comment.Line(comment.End))
+		if comment.Start != nil {
+			location = fmt.Sprintf("%s:%d-%d", comment.Path, comment.Line(*comment.Start), comment.Line(comment.End))
+		}
+		if comment.Side == scm.SideLeft {
+			location += " (old)"
+		}
+
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", location, comment.Body)
+	}
+
+	return b.String()
+}
+
+// writeOutput writes the output of a dry run to the configured file, or to
+// standard output when no file is configured.
+func (e *Engine) writeOutput(content string) error {
+	if e.cfg.Review.Output == "" {
+		if _, err := fmt.Fprint(e.stdout, content); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(e.cfg.Review.Output, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write output [%s]: %w", e.cfg.Review.Output, err)
+	}
+
+	slog.Info("Output written", "path", e.cfg.Review.Output)
+	return nil
+}
@@ -0,0 +1,166 @@
+package reviewer_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_DryRun(t *testing.T) {
+	rawDiff := fileDiff("a.go", 1) + fileDiff("b.go", 1) + fileDiff("c.go", 1)
+
+	newEngine := func(t *testing.T, opts ...func(*config.Config)) (*reviewer.Engine, *MockSCMClient, *MockLLMClient, *bytes.Buffer) {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(tempDir, "general.md"), []byte("Review {{ .Title }}:\n{{ .RawDiff }}"), 0644)
+		require.NoError(t, err)
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			LLM: config.LLM{
+				MaxTokens:     100,
+				ContextWindow: 100000,
+			},
+			Review: config.Review{
+				PromptType:      "general",
+				PromptDir:       tempDir,
+				CommentStrategy: config.CommentStrategyAppend,
+				DryRun:          true,
+			},
+		}
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := &MockLLMClient{models: []string{"gemini/gemini-2.5-flash"}}
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, "owner", "repo", 123).
+			Return(&scm.PullRequest{
+				Number:  123,
+				Title:   "Big change",
+				HeadSHA: "abc123",
+				RawDiff: rawDiff,
+				Files:   diff.Parse(rawDiff),
+			}, nil)
+
+		var stdout bytes.Buffer
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+		engine.SetOutput(&stdout)
+
+		return engine, mockSCMClient, mockLLMClient, &stdout
+	}
+
+	assertNothingPosted := func(t *testing.T, mockSCMClient *MockSCMClient) {
+		t.Helper()
+
+		mockSCMClient.AssertNotCalled(t, "PostReview", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockSCMClient.AssertNotCalled(t, "PostIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	t.Run("Success_WriteReviewToStdout", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient, stdout := newEngine(t)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return("Looks good.\n\n```findings\n[{\"file\":\"a.go\",\"start_line\":1,\"end_line\":2,\"title\":\"Long lines\",\"rationale\":\"Wrap them.\"}]\n```", nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\nLooks good.\n\n"+
+			"---\n<sub>Reviewed by ELGTM using `gemini/gemini-2.5-flash`.</sub>\n\n"+
+			"# Inline Comments (1)\n\n"+
+			"## a.go:1-2\n\n**Long lines**\n\nWrap them.\n", stdout.String())
+		assertNothingPosted(t, mockSCMClient)
+	})
+
+	t.Run("Success_WriteReviewToFile", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "review.md")
+		engine, mockSCMClient, mockLLMClient, stdout := newEngine(t, func(cfg *config.Config) {
+			cfg.Review.Output = output
+		})
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).Return("Looks good.", nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, stdout.String())
+
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\nLooks good."))
+		assert.NotContains(t, string(content), "# Inline Comments")
+		assertNothingPosted(t, mockSCMClient)
+	})
+
+	t.Run("Success_RenderOnly", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient, stdout := newEngine(t, func(cfg *config.Config) {
+			cfg.Review.DryRun = false
+			cfg.Review.RenderOnly = true
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Review Big change:\n"+rawDiff+"\n", stdout.String())
+		mockLLMClient.AssertNotCalled(t, "GenerateContent", mock.Anything, mock.Anything)
+		assertNothingPosted(t, mockSCMClient)
+	})
+
+	t.Run("Success_RenderOnlyStructured", func(t *testing.T) {
+		engine, _, mockLLMClient, stdout := newEngine(t, func(cfg *config.Config) {
+			cfg.Review.RenderOnly = true
+			cfg.Review.OutputMode = config.OutputModeStructured
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "# Response Format")
+		mockLLMClient.AssertNotCalled(t, "GenerateJSON", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success_RenderOnlyChunks", func(t *testing.T) {
+		engine, _, mockLLMClient, stdout := newEngine(t, func(cfg *config.Config) {
+			cfg.LLM.ContextWindow = 700
+			cfg.Review.RenderOnly = true
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "<!-- elgtm:prompt chunk=1/2 files=2 -->\nReview Big change:\n")
+		assert.Contains(t, stdout.String(), "<!-- elgtm:prompt chunk=2/2 files=1 -->\nReview Big change:\n")
+		mockLLMClient.AssertNotCalled(t, "GenerateContent", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure_FailedToWriteOutput", func(t *testing.T) {
+		engine, _, mockLLMClient, _ := newEngine(t, func(cfg *config.Config) {
+			cfg.Review.Output = filepath.Join(t.TempDir(), "missing", "review.md")
+		})
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).Return("Looks good.", nil)
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to write output")
+	})
+}
@@ -3,20 +3,22 @@ package reviewer
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/llm"
 	"github.com/fzl-22/elgtm/internal/scm"
-	"github.com/fzl-22/elgtm/internal/tmpl"
 )
 
 type Engine struct {
 	cfg       config.Config
 	scmClient scm.Client
 	llmClient llm.Client
+	stdout    io.Writer
 }
 
 func NewEngine(cfg config.Config, scmClient scm.Client, llmClient llm.Client) *Engine {
@@ -24,9 +26,16 @@ func NewEngine(cfg config.Config, scmClient scm.Client, llmClient llm.Client) *E
 		cfg:       cfg,
 		scmClient: scmClient,
 		llmClient: llmClient,
+		stdout:    os.Stdout,
 	}
 }
 
+// SetOutput sets where dry runs write their output when no output file is
+// configured. It defaults to standard output.
+func (e *Engine) SetOutput(w io.Writer) {
+	e.stdout = w
+}
+
 func (e *Engine) Run(ctx context.Context) error {
 	promptPath, err := e.ResolvePromptPath(e.cfg.Review.PromptDir, e.cfg.Review.PromptType)
 	if err != nil {
@@ -49,28 +58,178 @@ func (e *Engine) Run(ctx context.Context) error {
 
 	slog.Info("PR Fetched", "pr_number", pr.Number, "title", pr.Title, "author", pr.Author, "diff_size", len(pr.RawDiff))
 
-	prompt, err := tmpl.Generate(e.cfg.Review.PromptType, string(promptContent), *pr)
+	previous, err := e.previousSummaries(ctx)
 	if err != nil {
-		return fmt.Errorf("prompt generation failed: %w", err)
+		return err
+	}
+
+	reviewed := pr
+	if e.cfg.Review.Incremental {
+		reviewed, err = e.incrementalPullRequest(ctx, pr, previous)
+		if err != nil {
+			return err
+		}
+		if reviewed == nil {
+			slog.Info("No changes since the last review", "pr_number", pr.Number, "head_sha", pr.HeadSHA)
+			return nil
+		}
+	}
+
+	target := e.ignoreFiles(*reviewed)
+	if len(target.Files) == 0 && len(reviewed.Files) > 0 {
+		slog.Info("All changed files are ignored", "pr_number", pr.Number)
+		return nil
+	}
+
+	if e.cfg.Review.RenderOnly {
+		rendered, err := e.renderPrompts(string(promptContent), target)
+		if err != nil {
+			return err
+		}
+		return e.writeOutput(rendered)
+	}
+
+	summary, findings, err := e.reviewPullRequest(ctx, string(promptContent), target)
+	if err != nil {
+		return err
+	}
+
+	// Findings are anchored to the full diff of the pull request, which is
+	// where inline comments are placed even in an incremental review.
+	comments, unanchored := AnchorFindings(findings, pr.Files)
+
+	slog.Info("Findings anchored", "total", len(findings), "inline", len(comments), "unanchored", len(unanchored))
+
+	body := renderSummary(summary, unanchored)
+	if reviewed != pr {
+		body = fmt.Sprintf("_Reviewed the changes since %s._\n\n%s", shortSHA(reviewed.BaseSHA), body)
+	}
+	body += renderFooter(e.llmClient.Models())
+	// The summary comment replacing the previous one keeps its findings,
+	// since an incremental review only covers the newer commits.
+	if reviewed != pr && e.cfg.Review.CommentStrategy != config.CommentStrategyAppend {
+		body += earlierReviews(previous)
 	}
 
-	slog.Info("Prompt Generated", "length", len(prompt))
+	if e.cfg.Review.DryRun {
+		return e.writeOutput(e.renderDryRun(body, pr, comments))
+	}
+
+	if len(comments) > 0 {
+		slog.Info("Posting review", "repo", e.cfg.SCM.Repo, "pr", e.cfg.SCM.PRNumber, "comments", len(comments))
+
+		err = e.scmClient.PostReview(ctx, e.cfg.SCM.Owner, e.cfg.SCM.Repo, e.cfg.SCM.PRNumber, &scm.Review{
+			CommitID: pr.HeadSHA,
+			Comments: comments,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post review: %w", err)
+		}
+	}
+
+	return e.publishSummary(ctx, body, pr, previous)
+}
+
+// structuredOutputInstructions is appended to the prompt in structured output
+// mode, where the response schema replaces the prompt's own output format.
+const structuredOutputInstructions = `
+
+# Response Format
+Respond only with a JSON object matching the provided response schema, ignoring any other output format described above.
+Put the overall Markdown review in "summary" and list every issue that refers to specific lines of the diff in "findings".`
+
+const repairInstructions = `
+
+# Correction
+Your previous response could not be used: %v
+Respond again with only a valid JSON object matching the response schema.`
+
+func (e *Engine) generateReview(ctx context.Context, prompt string) (string, []Finding, error) {
+	if e.cfg.Review.OutputMode == config.OutputModeStructured {
+		return e.generateStructuredReview(ctx, prompt)
+	}
 
 	reviewBody, err := e.llmClient.GenerateContent(ctx, prompt)
 	if err != nil {
-		return fmt.Errorf("failed to generate review: %w", err)
+		return "", nil, fmt.Errorf("failed to generate review: %w", err)
+	}
+
+	summary, findings, err := ParseFindings(reviewBody)
+	if err != nil {
+		slog.Warn("Failed to parse findings, posting review as summary only", "error", err)
 	}
 
-	slog.Info("Posting comment", "repo", e.cfg.SCM.Repo, "pr", e.cfg.SCM.PRNumber)
+	return summary, findings, nil
+}
+
+// generateStructuredReview asks for a schema-constrained review and re-prompts
+// once, quoting the problem, if the response cannot be parsed or validated.
+func (e *Engine) generateStructuredReview(ctx context.Context, prompt string) (string, []Finding, error) {
+	prompt += structuredOutputInstructions
 
-	err = e.scmClient.PostIssueComment(ctx, e.cfg.SCM.Owner, e.cfg.SCM.Repo, e.cfg.SCM.PRNumber, &scm.IssueComment{
-		Body: &reviewBody,
-	})
+	content, err := e.llmClient.GenerateJSON(ctx, prompt, ReviewSchema)
 	if err != nil {
-		return fmt.Errorf("failed to post issue comment: %w", err)
+		return "", nil, fmt.Errorf("failed to generate review: %w", err)
+	}
+
+	review, err := ParseStructuredReview(content)
+	if err != nil {
+		slog.Warn("Invalid structured review, re-prompting", "error", err)
+
+		content, err = e.llmClient.GenerateJSON(ctx, prompt+fmt.Sprintf(repairInstructions, err), ReviewSchema)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate review: %w", err)
+		}
+
+		review, err = ParseStructuredReview(content)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse structured review: %w", err)
+		}
+	}
+
+	return strings.TrimSpace(review.Summary), review.Findings, nil
+}
+
+// renderSummary appends the findings that could not be placed on the diff to
+// the summary, so that nothing the model reported is lost.
+func renderSummary(summary string, unanchored []Finding) string {
+	if len(unanchored) == 0 {
+		return summary
+	}
+
+	var b strings.Builder
+	b.WriteString(summary)
+	if summary != "" {
+		b.WriteString("\n\n")
+	}
+	b.WriteString("### Other Findings\n")
+
+	for _, finding := range unanchored {
+		fmt.Fprintf(&b, "\n* `%s`", finding.Location())
+		if heading := finding.Heading(); heading != "" {
+			fmt.Fprintf(&b, " **%s**", heading)
+		}
+		if finding.Rationale != "" {
+			fmt.Fprintf(&b, ": %s", finding.Rationale)
+		}
+	}
+
+	return b.String()
+}
+
+// renderFooter names the models that wrote the review, which may differ from
+// the configured one when a fallback model took over.
+func renderFooter(models []string) string {
+	if len(models) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, 0, len(models))
+	for _, model := range models {
+		quoted = append(quoted, "`"+model+"`")
 	}
 
-	return nil
+	return "\n\n---\n<sub>Reviewed by ELGTM using " + strings.Join(quoted, ", ") + ".</sub>"
 }
 
 func (e *Engine) ResolvePromptPath(userDir, promptType string) (string, error) {
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/llm"
 	"github.com/fzl-22/elgtm/internal/reviewer"
 	"github.com/fzl-22/elgtm/internal/scm"
 	"github.com/stretchr/testify/assert"
@@ -31,8 +34,46 @@ func (m *MockSCMClient) PostIssueComment(ctx context.Context, owner, repo string
 	return args.Error(0)
 }
 
+func (m *MockSCMClient) PostReview(ctx context.Context, owner, repo string, number int, review *scm.Review) error {
+	args := m.Called(ctx, owner, repo, number, review)
+	return args.Error(0)
+}
+
+func (m *MockSCMClient) ListIssueComments(ctx context.Context, owner, repo string, number int) ([]*scm.IssueComment, error) {
+	args := m.Called(ctx, owner, repo, number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*scm.IssueComment), args.Error(1)
+}
+
+func (m *MockSCMClient) UpdateIssueComment(ctx context.Context, owner, repo string, number int, comment *scm.IssueComment) error {
+	args := m.Called(ctx, owner, repo, number, comment)
+	return args.Error(0)
+}
+
+func (m *MockSCMClient) DeleteIssueComment(ctx context.Context, owner, repo string, number int, commentID int64) error {
+	args := m.Called(ctx, owner, repo, number, commentID)
+	return args.Error(0)
+}
+
+func (m *MockSCMClient) CompareCommits(ctx context.Context, owner, repo, base, head string) (*scm.Comparison, error) {
+	args := m.Called(ctx, owner, repo, base, head)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*scm.Comparison), args.Error(1)
+}
+
+func (m *MockSCMClient) GetCurrentUser(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
 type MockLLMClient struct {
 	mock.Mock
+
+	models []string
 }
 
 func (m *MockLLMClient) GenerateContent(ctx context.Context, prompt string) (string, error) {
@@ -40,6 +81,15 @@ func (m *MockLLMClient) GenerateContent(ctx context.Context, prompt string) (str
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockLLMClient) GenerateJSON(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
+	args := m.Called(ctx, prompt, schema)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLLMClient) Models() []string {
+	return m.models
+}
+
 func TestEngine_NewEngine(t *testing.T) {
 	t.Run("Success_InitEngine", func(t *testing.T) {
 		cfg := config.Config{}
@@ -94,7 +144,7 @@ func TestEngine_Run(t *testing.T) {
 			Return("Looks Good To Me!", nil)
 
 		mockSCMClient.On("PostIssueComment", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(c *scm.IssueComment) bool {
-			return *c.Body == "Looks Good To Me!"
+			return *c.Body == "<!-- elgtm:review prompt_type=general -->\nLooks Good To Me!"
 		})).Return(nil)
 
 		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
@@ -107,6 +157,212 @@ func TestEngine_Run(t *testing.T) {
 		mockLLMClient.AssertExpectations(t)
 	})
 
+	t.Run("Success_ShowModelsInFooter", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := &MockLLMClient{models: []string{"gemini/gemini-2.5-pro", "anthropic/claude-sonnet-4-5"}}
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number: 123,
+			}, nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general -->\nLooks Good To Me!\n\n---\n"+
+				"<sub>Reviewed by ELGTM using `gemini/gemini-2.5-pro`, `anthropic/claude-sonnet-4-5`.</sub>"
+		})).Return(nil)
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_PostInlineFindings", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+			},
+		}
+
+		rawDiff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,3 @@\n package main\n+\n+func main() {}\n"
+		llmResponse := "## Summary\nAdds main.\n\n```findings\n" +
+			`[{"file":"main.go","start_line":3,"end_line":3,"title":"Empty main","rationale":"Do something."},` +
+			`{"file":"other.go","start_line":1,"end_line":1,"title":"Elsewhere","rationale":"Not in diff."}]` +
+			"\n```"
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number:  123,
+				HeadSHA: "abc123",
+				RawDiff: rawDiff,
+				Files:   diff.Parse(rawDiff),
+			}, nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return(llmResponse, nil)
+
+		mockSCMClient.On("PostReview", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(r *scm.Review) bool {
+			return r.CommitID == "abc123" &&
+				len(r.Comments) == 1 &&
+				r.Comments[0].Path == "main.go" &&
+				r.Comments[0].End == scm.DiffLine{NewLine: 3} &&
+				r.Comments[0].Side == scm.SideRight
+		})).Return(nil)
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return strings.HasPrefix(*c.Body, "<!-- elgtm:review prompt_type=general head_sha=abc123 -->\n## Summary\nAdds main.") &&
+				strings.Contains(*c.Body, "`other.go:1` **Elsewhere**: Not in diff.") &&
+				!strings.Contains(*c.Body, "Empty main")
+		})).Return(nil)
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_StructuredOutputMode", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+				OutputMode: config.OutputModeStructured,
+			},
+		}
+
+		rawDiff := "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number:  123,
+				RawDiff: rawDiff,
+				Files:   diff.Parse(rawDiff),
+			}, nil)
+
+		mockLLMClient.On("GenerateJSON", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.HasPrefix(prompt, "Hello! This is PR 123") && strings.Contains(prompt, "# Response Format")
+		}), reviewer.ReviewSchema).
+			Return(`{"summary": "Replaces old.", "findings": [{"file": "main.go", "start_line": 1, "end_line": 1, "side": "RIGHT", "severity": "minor", "category": "style", "title": "Naming", "rationale": "Use a clearer name."}]}`, nil).
+			Once()
+
+		mockSCMClient.On("PostReview", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(r *scm.Review) bool {
+			return len(r.Comments) == 1 && strings.HasPrefix(r.Comments[0].Body, "**🟢 Minor: Naming**")
+		})).Return(nil)
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general -->\nReplaces old."
+		})).Return(nil)
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_StructuredOutputRepromptOnce", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+				OutputMode: config.OutputModeStructured,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number: 123,
+			}, nil)
+
+		mockLLMClient.On("GenerateJSON", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return !strings.Contains(prompt, "# Correction")
+		}), reviewer.ReviewSchema).
+			Return(`{"summary": "truncated`, nil).
+			Once()
+
+		mockLLMClient.On("GenerateJSON", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "# Correction")
+		}), reviewer.ReviewSchema).
+			Return(`{"summary": "Looks Good To Me!", "findings": []}`, nil).
+			Once()
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general -->\nLooks Good To Me!"
+		})).Return(nil)
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
 	t.Run("Failure_PromptResolutionFailed", func(t *testing.T) {
 		cfg := config.Config{
 			Review: config.Review{
@@ -267,6 +523,88 @@ func TestEngine_Run(t *testing.T) {
 		mockLLMClient.AssertExpectations(t)
 	})
 
+	t.Run("Failure_StructuredOutputStillInvalid", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+				OutputMode: config.OutputModeStructured,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number: 123,
+			}, nil)
+
+		mockLLMClient.On("GenerateJSON", mock.Anything, mock.AnythingOfType("string"), reviewer.ReviewSchema).
+			Return("not json", nil).
+			Twice()
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse structured review")
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToPostReview", func(t *testing.T) {
+		tempDir := t.TempDir()
+		promptPath := filepath.Join(tempDir, "general.md")
+		createFile(t, promptPath, []byte("Hello! This is PR {{ .Number }}"))
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType: "general",
+				PromptDir:  tempDir,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber).
+			Return(&scm.PullRequest{
+				Number: 123,
+				Files:  diff.Parse("--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"),
+			}, nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.AnythingOfType("string")).
+			Return("Summary\n```findings\n[{\"file\":\"main.go\",\"start_line\":1,\"end_line\":1,\"rationale\":\"Bad\"}]\n```", nil)
+
+		mockSCMClient.On("PostReview", mock.Anything, cfg.SCM.Owner, cfg.SCM.Repo, cfg.SCM.PRNumber, mock.Anything).
+			Return(fmt.Errorf("failed to post review"))
+
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to post review")
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
 	t.Run("Failure_FailedToPostIssueComment", func(t *testing.T) {
 		tempDir := t.TempDir()
 		promptPath := filepath.Join(tempDir, "general.md")
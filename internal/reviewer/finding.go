@@ -0,0 +1,233 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/llm"
+)
+
+// findingsBlockPattern matches the fenced block in which text prompts ask the
+// model to list the findings that should be posted next to the code.
+var findingsBlockPattern = regexp.MustCompile("(?s)```findings[ \\t]*\\r?\\n(.*?)\\r?\\n?```")
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityMajor    Severity = "major"
+	SeverityMinor    Severity = "minor"
+)
+
+var severityLabels = map[Severity]string{
+	SeverityCritical: "🔴 Critical",
+	SeverityMajor:    "🟡 Major",
+	SeverityMinor:    "🟢 Minor",
+}
+
+type Finding struct {
+	File           string   `json:"file"`
+	StartLine      int      `json:"start_line"`
+	EndLine        int      `json:"end_line"`
+	Side           string   `json:"side,omitempty"`
+	Severity       Severity `json:"severity,omitempty"`
+	Category       string   `json:"category,omitempty"`
+	Title          string   `json:"title"`
+	Rationale      string   `json:"rationale"`
+	SuggestedPatch string   `json:"suggested_patch,omitempty"`
+}
+
+// StructuredReview is the JSON document the model returns in structured
+// output mode.
+type StructuredReview struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+}
+
+// ReviewSchema constrains the model output to a StructuredReview.
+var ReviewSchema = &llm.Schema{
+	Type:     llm.TypeObject,
+	Required: []string{"summary", "findings"},
+	Properties: map[string]*llm.Schema{
+		"summary": {
+			Type:        llm.TypeString,
+			Description: "Markdown summary of the changes and of the overall review.",
+		},
+		"findings": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"file", "start_line", "end_line", "side", "severity", "category", "title", "rationale"},
+				Properties: map[string]*llm.Schema{
+					"file": {
+						Type:        llm.TypeString,
+						Description: "Path of the file as shown in the diff, without the a/ or b/ prefix.",
+					},
+					"start_line": {
+						Type:        llm.TypeInteger,
+						Description: "First line the finding refers to, or 0 if it applies to the whole file.",
+					},
+					"end_line": {
+						Type:        llm.TypeInteger,
+						Description: "Last line the finding refers to. Equal to start_line for single-line findings.",
+					},
+					"side": {
+						Type:        llm.TypeString,
+						Description: "RIGHT for lines of the new version of the file, LEFT for removed lines.",
+						Enum:        []string{"RIGHT", "LEFT"},
+					},
+					"severity": {
+						Type: llm.TypeString,
+						Enum: []string{string(SeverityCritical), string(SeverityMajor), string(SeverityMinor)},
+					},
+					"category": {
+						Type:        llm.TypeString,
+						Description: "Kind of issue, e.g. bug, security, performance, maintainability or style.",
+					},
+					"title": {
+						Type:        llm.TypeString,
+						Description: "One-line description of the issue.",
+					},
+					"rationale": {
+						Type:        llm.TypeString,
+						Description: "Markdown explanation of why this is an issue and how to fix it.",
+					},
+					"suggested_patch": {
+						Type:        llm.TypeString,
+						Description: "Optional replacement for the lines from start_line to end_line.",
+					},
+				},
+			},
+		},
+	},
+}
+
+// ParseFindings splits a text review into its free-form summary and the
+// findings listed in its "findings" block. A review without a valid block is
+// returned unchanged as the summary.
+func ParseFindings(review string) (string, []Finding, error) {
+	match := findingsBlockPattern.FindStringSubmatchIndex(review)
+	if match == nil {
+		return strings.TrimSpace(review), nil, nil
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal([]byte(review[match[2]:match[3]]), &findings); err != nil {
+		return strings.TrimSpace(review), nil, fmt.Errorf("failed to decode findings block: %w", err)
+	}
+
+	if err := validateFindings(findings); err != nil {
+		return strings.TrimSpace(review), nil, err
+	}
+
+	summary := review[:match[0]] + review[match[1]:]
+
+	return strings.TrimSpace(summary), findings, nil
+}
+
+// ParseStructuredReview decodes and validates a structured review. Markdown
+// code fences and text around the JSON object, which some models add despite
+// the response schema, are stripped before decoding.
+func ParseStructuredReview(content string) (*StructuredReview, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start == -1 || end < start {
+		return nil, errors.New("response does not contain a JSON object")
+	}
+
+	var review StructuredReview
+	if err := json.Unmarshal([]byte(content[start:end+1]), &review); err != nil {
+		return nil, fmt.Errorf("failed to decode structured review: %w", err)
+	}
+
+	if err := validateFindings(review.Findings); err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+func validateFindings(findings []Finding) error {
+	var errs []error
+	for i, finding := range findings {
+		if err := finding.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("finding %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f Finding) Validate() error {
+	var errs []error
+
+	if f.File == "" {
+		errs = append(errs, errors.New("file is required"))
+	}
+	if f.Title == "" && f.Rationale == "" {
+		errs = append(errs, errors.New("title or rationale is required"))
+	}
+	if f.StartLine < 0 {
+		errs = append(errs, fmt.Errorf("start_line must not be negative, got %d", f.StartLine))
+	}
+	if f.EndLine != 0 && f.EndLine < f.StartLine {
+		errs = append(errs, fmt.Errorf("end_line %d is before start_line %d", f.EndLine, f.StartLine))
+	}
+	if f.Side != "" && !strings.EqualFold(f.Side, "LEFT") && !strings.EqualFold(f.Side, "RIGHT") {
+		errs = append(errs, fmt.Errorf("side must be LEFT or RIGHT, got %q", f.Side))
+	}
+	if _, ok := severityLabels[f.Severity]; f.Severity != "" && !ok {
+		errs = append(errs, fmt.Errorf("unknown severity %q", f.Severity))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f Finding) Location() string {
+	if f.EndLine > f.StartLine {
+		return fmt.Sprintf("%s:%d-%d", f.File, f.StartLine, f.EndLine)
+	}
+	if f.StartLine > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.StartLine)
+	}
+	return f.File
+}
+
+// Heading returns the severity and title of the finding as a single line.
+func (f Finding) Heading() string {
+	label, ok := severityLabels[f.Severity]
+	switch {
+	case ok && f.Title != "":
+		return fmt.Sprintf("%s: %s", label, f.Title)
+	case ok:
+		return label
+	default:
+		return f.Title
+	}
+}
+
+// Markdown renders the finding as the body of an inline review comment.
+func (f Finding) Markdown() string {
+	var b strings.Builder
+
+	if heading := f.Heading(); heading != "" {
+		fmt.Fprintf(&b, "**%s**\n\n", heading)
+	}
+	if f.Category != "" {
+		fmt.Fprintf(&b, "_Category: %s_\n\n", f.Category)
+	}
+	b.WriteString(f.Rationale)
+	if f.SuggestedPatch != "" {
+		// Suggestions replace the commented lines, which only makes sense for
+		// lines of the new version of the file.
+		fence := "suggestion"
+		if strings.EqualFold(f.Side, "LEFT") {
+			fence = ""
+		}
+		fmt.Fprintf(&b, "\n\n```%s\n%s\n```", fence, strings.TrimRight(f.SuggestedPatch, "\n"))
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,134 @@
+package reviewer_test
+
+import (
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinding_ParseFindings(t *testing.T) {
+	t.Run("Success_NoFindingsBlock", func(t *testing.T) {
+		summary, findings, err := reviewer.ParseFindings("  Looks Good To Me!\n")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Looks Good To Me!", summary)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("Success_ExtractFindingsBlock", func(t *testing.T) {
+		review := "## Summary\nOK\n\n```findings\n[{\"file\":\"a.go\",\"start_line\":1,\"end_line\":2,\"side\":\"LEFT\",\"title\":\"T\",\"rationale\":\"B\"}]\n```\n"
+
+		summary, findings, err := reviewer.ParseFindings(review)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "## Summary\nOK", summary)
+		require.Len(t, findings, 1)
+		assert.Equal(t, reviewer.Finding{File: "a.go", StartLine: 1, EndLine: 2, Side: "LEFT", Title: "T", Rationale: "B"}, findings[0])
+	})
+
+	t.Run("Failure_InvalidFindingsBlock", func(t *testing.T) {
+		review := "Summary\n```findings\nnot json\n```"
+
+		summary, findings, err := reviewer.ParseFindings(review)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode findings block")
+		assert.Equal(t, review, summary)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("Failure_InvalidFinding", func(t *testing.T) {
+		review := "Summary\n```findings\n[{\"start_line\":1,\"title\":\"T\"}]\n```"
+
+		summary, findings, err := reviewer.ParseFindings(review)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "finding 0: file is required")
+		assert.Equal(t, review, summary)
+		assert.Empty(t, findings)
+	})
+}
+
+func TestFinding_ParseStructuredReview(t *testing.T) {
+	t.Run("Success_ParseStructuredReview", func(t *testing.T) {
+		content := `{"summary": "Adds main.", "findings": [{"file": "main.go", "start_line": 3, "end_line": 4, "side": "RIGHT", "severity": "critical", "category": "bug", "title": "Nil map", "rationale": "Initialize it.", "suggested_patch": "m := map[string]int{}"}]}`
+
+		review, err := reviewer.ParseStructuredReview(content)
+
+		assert.NoError(t, err)
+		require.NotNil(t, review)
+		assert.Equal(t, "Adds main.", review.Summary)
+		assert.Equal(t, []reviewer.Finding{{
+			File:           "main.go",
+			StartLine:      3,
+			EndLine:        4,
+			Side:           "RIGHT",
+			Severity:       reviewer.SeverityCritical,
+			Category:       "bug",
+			Title:          "Nil map",
+			Rationale:      "Initialize it.",
+			SuggestedPatch: "m := map[string]int{}",
+		}}, review.Findings)
+	})
+
+	t.Run("Success_RepairCodeFence", func(t *testing.T) {
+		content := "Here is the review:\n```json\n{\"summary\": \"OK\", \"findings\": []}\n```\n"
+
+		review, err := reviewer.ParseStructuredReview(content)
+
+		assert.NoError(t, err)
+		require.NotNil(t, review)
+		assert.Equal(t, "OK", review.Summary)
+		assert.Empty(t, review.Findings)
+	})
+
+	t.Run("Failure_NoJSONObject", func(t *testing.T) {
+		review, err := reviewer.ParseStructuredReview("Looks Good To Me!")
+
+		assert.Error(t, err)
+		assert.Nil(t, review)
+		assert.Contains(t, err.Error(), "does not contain a JSON object")
+	})
+
+	t.Run("Failure_MalformedJSON", func(t *testing.T) {
+		review, err := reviewer.ParseStructuredReview(`{"summary": "OK", "findings": [}`)
+
+		assert.Error(t, err)
+		assert.Nil(t, review)
+		assert.Contains(t, err.Error(), "failed to decode structured review")
+	})
+
+	t.Run("Failure_InvalidFindings", func(t *testing.T) {
+		content := `{"summary": "OK", "findings": [{"file": "a.go", "start_line": 5, "end_line": 2, "side": "UP", "severity": "blocker", "title": "T"}]}`
+
+		review, err := reviewer.ParseStructuredReview(content)
+
+		assert.Error(t, err)
+		assert.Nil(t, review)
+		assert.Contains(t, err.Error(), "end_line 2 is before start_line 5")
+		assert.Contains(t, err.Error(), `side must be LEFT or RIGHT, got "UP"`)
+		assert.Contains(t, err.Error(), `unknown severity "blocker"`)
+	})
+}
+
+func TestFinding_Markdown(t *testing.T) {
+	t.Run("Success_RenderFullFinding", func(t *testing.T) {
+		finding := reviewer.Finding{
+			Severity:       reviewer.SeverityMajor,
+			Category:       "bug",
+			Title:          "Off by one",
+			Rationale:      "The loop skips the last element.",
+			SuggestedPatch: "for i := 0; i <= n; i++ {\n",
+		}
+
+		assert.Equal(t, "**🟡 Major: Off by one**\n\n_Category: bug_\n\nThe loop skips the last element.\n\n```suggestion\nfor i := 0; i <= n; i++ {\n```", finding.Markdown())
+	})
+
+	t.Run("Success_RenderLeftSidePatchAsCode", func(t *testing.T) {
+		finding := reviewer.Finding{Side: "LEFT", Title: "Removed check", Rationale: "Keep it.", SuggestedPatch: "if err != nil {}"}
+
+		assert.Equal(t, "**Removed check**\n\nKeep it.\n\n```\nif err != nil {}\n```", finding.Markdown())
+	})
+}
@@ -0,0 +1,125 @@
+package reviewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+)
+
+// maxEarlierReviewsSize bounds the size of the earlier reviews carried by a
+// summary comment, well within the comment size limits of the platforms. The
+// oldest reviews are dropped first.
+const maxEarlierReviewsSize = 32 << 10
+
+const (
+	earlierReviewsStart     = "\n\n<details>\n<summary>Earlier reviews</summary>\n\n"
+	earlierReviewsEnd       = "\n\n</details>"
+	earlierReviewsSeparator = "<!-- elgtm:earlier_review -->\n"
+)
+
+// incrementalPullRequest narrows the pull request to the changes pushed since
+// the head commit recorded in the latest previous summary. It returns the pull
+// request unchanged when there is no earlier review to build on, when the base
+// of the pull request moved since, when that commit is no longer reachable
+// after a force-push or when the platform cannot compare commits, and nil when
+// nothing changed since.
+func (e *Engine) incrementalPullRequest(ctx context.Context, pr *scm.PullRequest, previous []*scm.IssueComment) (*scm.PullRequest, error) {
+	last, _ := lastReviewed(previous)
+	lastSHA := last.HeadSHA
+	if lastSHA == "" || pr.HeadSHA == "" {
+		return pr, nil
+	}
+	if lastSHA == pr.HeadSHA {
+		return nil, nil
+	}
+	// Once the base branch is merged or rebased onto, the commits since the
+	// last review include changes that are not part of the pull request.
+	if last.BaseSHA != pr.BaseSHA {
+		slog.Info("Base moved since the last review, falling back to a full review", "last_base_sha", last.BaseSHA, "base_sha", pr.BaseSHA)
+		return pr, nil
+	}
+
+	comparison, err := e.scmClient.CompareCommits(ctx, e.cfg.SCM.Owner, e.cfg.SCM.Repo, lastSHA, pr.HeadSHA)
+	if errors.Is(err, scm.ErrCommitUnreachable) {
+		slog.Warn("Last reviewed commit is unreachable, falling back to a full review", "last_sha", lastSHA, "head_sha", pr.HeadSHA, "error", err)
+		return pr, nil
+	}
+	if errors.Is(err, scm.ErrCompareUnsupported) {
+		slog.Debug("Platform cannot compare commits, falling back to a full review", "error", err)
+		return pr, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	}
+
+	if len(comparison.Files) == 0 {
+		return nil, nil
+	}
+
+	slog.Info("Reviewing changes since the last review", "last_sha", lastSHA, "head_sha", pr.HeadSHA, "files", len(comparison.Files), "diff_size", len(comparison.RawDiff))
+
+	incremental := *pr
+	incremental.BaseSHA = comparison.BaseSHA
+	incremental.RawDiff = comparison.RawDiff
+	incremental.Files = comparison.Files
+
+	return &incremental, nil
+}
+
+// lastReviewed returns the marker and the body of the newest summary comment
+// that records a head commit.
+func lastReviewed(previous []*scm.IssueComment) (Marker, string) {
+	for i := len(previous) - 1; i >= 0; i-- {
+		marker, _ := ParseMarker(*previous[i].Body)
+		if marker.HeadSHA != "" {
+			return marker, *previous[i].Body
+		}
+	}
+	return Marker{}, ""
+}
+
+// earlierReviews renders the previous summary, and the earlier reviews it
+// carries in turn, as a collapsed section to append to an incremental summary.
+func earlierReviews(previous []*scm.IssueComment) string {
+	last, body := lastReviewed(previous)
+	if body == "" {
+		return ""
+	}
+
+	loc := markerPattern.FindStringIndex(body)
+	body = body[:loc[0]] + strings.TrimPrefix(body[loc[1]:], "\n")
+	latest, carried, _ := strings.Cut(body, earlierReviewsStart)
+	carried = strings.TrimSuffix(carried, earlierReviewsEnd)
+
+	reviews := []string{fmt.Sprintf("**Review of %s**\n\n%s", shortSHA(last.HeadSHA), latest)}
+	for _, review := range strings.Split(carried, earlierReviewsSeparator) {
+		if review = strings.TrimSpace(review); review != "" {
+			reviews = append(reviews, review)
+		}
+	}
+
+	var b strings.Builder
+	for _, review := range reviews {
+		entry := earlierReviewsSeparator + review + "\n\n"
+		if b.Len()+len(entry) > maxEarlierReviewsSize {
+			break
+		}
+		b.WriteString(entry)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return earlierReviewsStart + strings.TrimSuffix(b.String(), "\n\n") + earlierReviewsEnd
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,262 @@
+package reviewer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncremental_Run(t *testing.T) {
+	fullDiff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1,2 @@\n package main\n+func a() {}\n" +
+		"diff --git a/util.go b/util.go\n--- a/util.go\n+++ b/util.go\n@@ -1 +1,2 @@\n package main\n+func b() {}\n"
+	newDiff := "diff --git a/util.go b/util.go\n--- a/util.go\n+++ b/util.go\n@@ -1 +1,2 @@\n package main\n+func b() {}\n"
+
+	newEngine := func(t *testing.T) (*reviewer.Engine, *MockSCMClient, *MockLLMClient) {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(tempDir, "general.md"), []byte("Review:\n{{ .RawDiff }}"), 0644)
+		require.NoError(t, err)
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType:      "general",
+				PromptDir:       tempDir,
+				CommentStrategy: config.CommentStrategyUpdate,
+				Incremental:     true,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, "owner", "repo", 123).
+			Return(&scm.PullRequest{
+				Number:  123,
+				BaseSHA: "base000",
+				HeadSHA: "def4567890",
+				RawDiff: fullDiff,
+				Files:   diff.Parse(fullDiff),
+			}, nil)
+
+		return reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient), mockSCMClient, mockLLMClient
+	}
+
+	comment := func(id int64, body string) *scm.IssueComment {
+		return &scm.IssueComment{ID: id, Body: &body, Author: "elgtm-bot"}
+	}
+
+	t.Run("Success_ReviewOnlyNewCommits", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(&scm.Comparison{BaseSHA: "abc1234567", HeadSHA: "def4567890", RawDiff: newDiff, Files: diff.Parse(newDiff)}, nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "util.go") && !strings.Contains(prompt, "main.go")
+		})).Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return c.ID == 1 && *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\n"+
+				"_Reviewed the changes since abc1234._\n\nLooks Good To Me!"+
+				"\n\n<details>\n<summary>Earlier reviews</summary>\n\n<!-- elgtm:earlier_review -->\n**Review of abc1234**\n\nOld review\n\n</details>"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_FullReviewWithoutPreviousReview", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general -->\nReview without a head SHA")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "util.go") && strings.Contains(prompt, "main.go")
+		})).Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\nLooks Good To Me!"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "CompareCommits", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_KeepAllEarlierReviews", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\n"+
+				"_Reviewed the changes since 0123456._\n\nSecond review"+
+				"\n\n<details>\n<summary>Earlier reviews</summary>\n\n<!-- elgtm:earlier_review -->\n**Review of 0123456**\n\nFirst review\n\n</details>")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(&scm.Comparison{BaseSHA: "abc1234567", HeadSHA: "def4567890", RawDiff: newDiff, Files: diff.Parse(newDiff)}, nil)
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).Return("Third review", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\n"+
+				"_Reviewed the changes since abc1234._\n\nThird review"+
+				"\n\n<details>\n<summary>Earlier reviews</summary>\n\n"+
+				"<!-- elgtm:earlier_review -->\n**Review of abc1234**\n\n_Reviewed the changes since 0123456._\n\nSecond review\n\n"+
+				"<!-- elgtm:earlier_review -->\n**Review of 0123456**\n\nFirst review\n\n</details>"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_DropOldestEarlierReviews", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\nSecond review"+
+				"\n\n<details>\n<summary>Earlier reviews</summary>\n\n<!-- elgtm:earlier_review -->\n"+strings.Repeat("x", 40_000)+"\n\n</details>")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(&scm.Comparison{BaseSHA: "abc1234567", HeadSHA: "def4567890", RawDiff: newDiff, Files: diff.Parse(newDiff)}, nil)
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).Return("Third review", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return strings.HasSuffix(*c.Body, "<!-- elgtm:earlier_review -->\n**Review of abc1234**\n\nSecond review\n\n</details>")
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_FullReviewAfterBaseMoved", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=oldbase head_sha=abc1234567 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "util.go") && strings.Contains(prompt, "main.go")
+		})).Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\nLooks Good To Me!"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "CompareCommits", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_FullReviewAfterForcePush", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(nil, fmt.Errorf("failed to compare commits using SCM driver: %w", scm.ErrCommitUnreachable))
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "util.go") && strings.Contains(prompt, "main.go")
+		})).Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\nLooks Good To Me!"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_FullReviewWhenCompareUnsupported", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(nil, fmt.Errorf("failed to compare commits using SCM driver: %w", scm.ErrCompareUnsupported))
+
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "util.go") && strings.Contains(prompt, "main.go")
+		})).Return("Looks Good To Me!", nil)
+
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\nLooks Good To Me!"
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_SkipWhenHeadAlreadyReviewed", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=def4567890 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertNotCalled(t, "GenerateContent", mock.Anything, mock.Anything)
+		mockSCMClient.AssertNotCalled(t, "UpdateIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure_FailedToCompareCommits", func(t *testing.T) {
+		engine, mockSCMClient, _ := newEngine(t)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{comment(1, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc1234567 -->\nOld review")}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("CompareCommits", mock.Anything, "owner", "repo", "abc1234567", "def4567890").
+			Return(nil, fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to compare commits")
+	})
+}
@@ -0,0 +1,49 @@
+package reviewer
+
+import (
+	"context"
+	"sync"
+)
+
+// runPool calls task for each index in [0, n) with at most concurrency calls
+// in flight, and returns the error of each call by index. Once ctx is
+// cancelled no new call is started and the remaining indexes fail with the
+// context's error; calls already in flight are expected to observe ctx.
+func runPool(ctx context.Context, n, concurrency int, task func(ctx context.Context, i int) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < n; j++ {
+				errs[j] = ctx.Err()
+			}
+			wg.Wait()
+			return errs
+		}
+
+		if err := ctx.Err(); err != nil {
+			<-sem
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs[i] = task(ctx, i)
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
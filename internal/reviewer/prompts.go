@@ -0,0 +1,76 @@
+package reviewer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Prompt is a prompt template available to reviews, named after its file.
+type Prompt struct {
+	Type string
+	Path string
+	// System is set for the prompts that ship with ELGTM, which the user's
+	// prompts of the same type override.
+	System bool
+}
+
+// ListPrompts returns the prompts found in the user's prompt directory and in
+// the system defaults, with the same precedence as ResolvePromptPath, sorted
+// by type.
+func ListPrompts(userDir string) ([]Prompt, error) {
+	user, err := findPrompts(userDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var system []Prompt
+	if systemDir := os.Getenv("PROMPT_DEFAULTS"); systemDir != "" {
+		system, err = findPrompts(systemDir, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prompts := user
+	for _, prompt := range system {
+		overridden := slices.ContainsFunc(user, func(p Prompt) bool { return p.Type == prompt.Type })
+		if !overridden {
+			prompts = append(prompts, prompt)
+		}
+	}
+
+	slices.SortFunc(prompts, func(a, b Prompt) int { return strings.Compare(a.Type, b.Type) })
+
+	return prompts, nil
+}
+
+func findPrompts(dir string, system bool) ([]Prompt, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts in [%s]: %w", dir, err)
+	}
+
+	var prompts []Prompt
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".md" {
+			continue
+		}
+
+		prompts = append(prompts, Prompt{
+			Type:   strings.TrimSuffix(name, ".md"),
+			Path:   filepath.Join(dir, name),
+			System: system,
+		})
+	}
+
+	return prompts, nil
+}
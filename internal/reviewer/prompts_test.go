@@ -0,0 +1,50 @@
+package reviewer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPrompts(t *testing.T) {
+	createFile := func(t *testing.T, dir, name string) {
+		t.Helper()
+		err := os.WriteFile(filepath.Join(dir, name), []byte("content"), 0644)
+		require.NoError(t, err)
+	}
+
+	t.Run("Success_UserPromptsOverrideSystemPrompts", func(t *testing.T) {
+		userDir := t.TempDir()
+		systemDir := t.TempDir()
+		createFile(t, userDir, "security.md")
+		createFile(t, userDir, "general.md")
+		createFile(t, userDir, "notes.txt")
+		createFile(t, systemDir, "general.md")
+		createFile(t, systemDir, "architecture.md")
+		require.NoError(t, os.Mkdir(filepath.Join(userDir, "nested.md"), 0755))
+
+		t.Setenv("PROMPT_DEFAULTS", systemDir)
+
+		prompts, err := reviewer.ListPrompts(userDir)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []reviewer.Prompt{
+			{Type: "architecture", Path: filepath.Join(systemDir, "architecture.md"), System: true},
+			{Type: "general", Path: filepath.Join(userDir, "general.md")},
+			{Type: "security", Path: filepath.Join(userDir, "security.md")},
+		}, prompts)
+	})
+
+	t.Run("Success_MissingUserDir", func(t *testing.T) {
+		t.Setenv("PROMPT_DEFAULTS", "")
+
+		prompts, err := reviewer.ListPrompts(filepath.Join(t.TempDir(), "missing"))
+
+		assert.NoError(t, err)
+		assert.Empty(t, prompts)
+	})
+}
@@ -0,0 +1,99 @@
+package reviewer
+
+import (
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/fzl-22/elgtm/internal/tmpl"
+)
+
+const rulesInstructions = `
+
+# Team Rules
+Also check the changes against the following rules of the team:
+`
+
+// generatePrompt renders the prompt template for the pull request and appends
+// the team's review rules.
+func (e *Engine) generatePrompt(promptContent string, pr scm.PullRequest) (string, error) {
+	prompt, err := tmpl.Generate(e.cfg.Review.PromptType, promptContent, pr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(e.cfg.Review.Rules) == 0 {
+		return prompt, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString(rulesInstructions)
+	for _, rule := range e.cfg.Review.Rules {
+		b.WriteString("* " + strings.TrimSpace(rule) + "\n")
+	}
+
+	return b.String(), nil
+}
+
+// ignoreFiles leaves the files matching the ignore patterns out of the pull
+// request, rebuilding its diff from the remaining files.
+func (e *Engine) ignoreFiles(pr scm.PullRequest) scm.PullRequest {
+	if len(e.cfg.Review.Ignore) == 0 {
+		return pr
+	}
+
+	var kept []scm.ChangedFile
+	var ignored []string
+	for _, file := range pr.Files {
+		if IsIgnored(file.Path(), e.cfg.Review.Ignore) {
+			ignored = append(ignored, file.Path())
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	if len(ignored) == 0 {
+		return pr
+	}
+
+	slog.Info("Ignoring files", "files", ignored)
+
+	pr.Files = kept
+	pr.RawDiff = diff.Format(kept)
+	return pr
+}
+
+// IsIgnored reports whether the path matches one of the ignore patterns.
+// Patterns without a slash match the file name in any directory, such as
+// "*.pb.go"; patterns ending in "/**" match everything under a directory, such
+// as "vendor/**"; other patterns match the whole path.
+func IsIgnored(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			for parent := path.Dir(p); parent != "." && parent != "/"; parent = path.Dir(parent) {
+				if matched, _ := path.Match(dir, parent); matched {
+					return true
+				}
+			}
+			continue
+		}
+
+		name := p
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(p)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
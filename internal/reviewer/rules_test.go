@@ -0,0 +1,119 @@
+package reviewer_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/diff"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_IsIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		patterns []string
+		expected bool
+	}{
+		{"Success_FileNameInAnyDirectory", "api/v1/service.pb.go", []string{"*.pb.go"}, true},
+		{"Success_WholePath", "docs/CHANGELOG.md", []string{"docs/*.md"}, true},
+		{"Success_WholePathDoesNotMatchDeeper", "docs/api/index.md", []string{"docs/*.md"}, false},
+		{"Success_Directory", "vendor/github.com/x/y.go", []string{"vendor/**"}, true},
+		{"Success_NestedDirectory", "web/node_modules/x/index.js", []string{"*/node_modules/**"}, true},
+		{"Success_LeadingSlash", "go.sum", []string{"/go.sum"}, true},
+		{"Success_NoMatch", "main.go", []string{"*.pb.go", "vendor/**"}, false},
+		{"Success_NoPatterns", "main.go", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, reviewer.IsIgnored(tt.path, tt.patterns))
+		})
+	}
+}
+
+func TestRules_Run(t *testing.T) {
+	rawDiff := fileDiff("main.go", 1) + fileDiff("api/service.pb.go", 1)
+
+	newEngine := func(t *testing.T, review config.Review) (*reviewer.Engine, *MockLLMClient, *bytes.Buffer) {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(tempDir, "general.md"), []byte("Review {{ .Title }}:\n{{ .RawDiff }}"), 0644)
+		require.NoError(t, err)
+
+		review.PromptType = "general"
+		review.PromptDir = tempDir
+		review.CommentStrategy = config.CommentStrategyAppend
+		review.RenderOnly = true
+
+		cfg := config.Config{
+			SCM:    config.SCM{Owner: "owner", Repo: "repo", PRNumber: 123},
+			LLM:    config.LLM{MaxTokens: 100, ContextWindow: 100000},
+			Review: review,
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, "owner", "repo", 123).
+			Return(&scm.PullRequest{
+				Number:  123,
+				Title:   "Change",
+				HeadSHA: "abc123",
+				RawDiff: rawDiff,
+				Files:   diff.Parse(rawDiff),
+			}, nil)
+
+		var stdout bytes.Buffer
+		engine := reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient)
+		engine.SetOutput(&stdout)
+
+		return engine, mockLLMClient, &stdout
+	}
+
+	t.Run("Success_AppendRulesToPrompt", func(t *testing.T) {
+		engine, _, stdout := newEngine(t, config.Review{
+			Rules: []string{"Prefer table-driven tests.", " Wrap errors with context. "},
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "\n\n# Team Rules\nAlso check the changes against the following rules of the team:\n"+
+			"* Prefer table-driven tests.\n* Wrap errors with context.\n")
+	})
+
+	t.Run("Success_LeaveIgnoredFilesOut", func(t *testing.T) {
+		engine, _, stdout := newEngine(t, config.Review{
+			Ignore: []string{"*.pb.go"},
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "b/main.go")
+		assert.NotContains(t, stdout.String(), "service.pb.go")
+		assert.NotContains(t, stdout.String(), "# Team Rules")
+	})
+
+	t.Run("Success_SkipWhenEveryFileIsIgnored", func(t *testing.T) {
+		engine, mockLLMClient, stdout := newEngine(t, config.Review{
+			Ignore: []string{"*.go"},
+		})
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, stdout.String())
+		mockLLMClient.AssertNotCalled(t, "GenerateContent", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,155 @@
+package reviewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/scm"
+)
+
+var markerPattern = regexp.MustCompile(`<!-- elgtm:review((?: [a-z_]+=\S*)*) -->`)
+
+// Marker is the hidden HTML comment embedded in summary comments so that
+// later runs can find the comment they posted.
+type Marker struct {
+	PromptType string
+	// BaseSHA is the base commit of the pull request that was reviewed.
+	BaseSHA string
+	// HeadSHA is the head commit of the pull request that was reviewed.
+	HeadSHA string
+}
+
+func (m Marker) String() string {
+	attrs := "prompt_type=" + m.PromptType
+	if m.BaseSHA != "" {
+		attrs += " base_sha=" + m.BaseSHA
+	}
+	if m.HeadSHA != "" {
+		attrs += " head_sha=" + m.HeadSHA
+	}
+	return fmt.Sprintf("<!-- elgtm:review %s -->", attrs)
+}
+
+// ParseMarker extracts the marker from a comment body.
+func ParseMarker(body string) (Marker, bool) {
+	match := markerPattern.FindStringSubmatch(body)
+	if match == nil {
+		return Marker{}, false
+	}
+
+	var marker Marker
+	for _, attr := range strings.Fields(match[1]) {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "prompt_type":
+			marker.PromptType = value
+		case "base_sha":
+			marker.BaseSHA = value
+		case "head_sha":
+			marker.HeadSHA = value
+		}
+	}
+
+	return marker, true
+}
+
+// previousSummaries returns the summary comments left by earlier runs of the
+// same prompt type, oldest first. Only the comments of the authenticated user
+// count, since anyone can quote a marker, so none do when that user is
+// unknown. The comments are only listed when
+// the comment strategy or an incremental review needs them.
+func (e *Engine) previousSummaries(ctx context.Context) ([]*scm.IssueComment, error) {
+	strategy := e.cfg.Review.CommentStrategy
+	if strategy != config.CommentStrategyUpdate && strategy != config.CommentStrategyReplace && !e.cfg.Review.Incremental {
+		return nil, nil
+	}
+
+	comments, err := e.scmClient.ListIssueComments(ctx, e.cfg.SCM.Owner, e.cfg.SCM.Repo, e.cfg.SCM.PRNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue comments: %w", err)
+	}
+
+	author, err := e.scmClient.GetCurrentUser(ctx)
+	if errors.Is(err, scm.ErrCurrentUserUnknown) {
+		slog.Warn("Cannot tell which comments were posted by ELGTM, posting a new summary", "error", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	return findSummaryComments(comments, e.cfg.Review.PromptType, author), nil
+}
+
+// publishSummary posts the summary comment according to the configured
+// comment strategy, reusing or removing the previous summary comments. The
+// marker records the commits of pr so that the next run can review only newer
+// commits.
+func (e *Engine) publishSummary(ctx context.Context, body string, pr *scm.PullRequest, previous []*scm.IssueComment) error {
+	body = e.marker(pr).String() + "\n" + body
+
+	owner, repo, number := e.cfg.SCM.Owner, e.cfg.SCM.Repo, e.cfg.SCM.PRNumber
+
+	switch e.cfg.Review.CommentStrategy {
+	case config.CommentStrategyUpdate:
+		if len(previous) > 0 {
+			latest := previous[len(previous)-1]
+			slog.Info("Updating comment", "repo", repo, "pr", number, "comment_id", latest.ID)
+
+			err := e.scmClient.UpdateIssueComment(ctx, owner, repo, number, &scm.IssueComment{
+				ID:   latest.ID,
+				Body: &body,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update issue comment: %w", err)
+			}
+			return nil
+		}
+	case config.CommentStrategyReplace:
+		for _, comment := range previous {
+			slog.Info("Deleting comment", "repo", repo, "pr", number, "comment_id", comment.ID)
+
+			if err := e.scmClient.DeleteIssueComment(ctx, owner, repo, number, comment.ID); err != nil {
+				return fmt.Errorf("failed to delete issue comment: %w", err)
+			}
+		}
+	}
+
+	slog.Info("Posting comment", "repo", repo, "pr", number)
+
+	err := e.scmClient.PostIssueComment(ctx, owner, repo, number, &scm.IssueComment{
+		Body: &body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post issue comment: %w", err)
+	}
+
+	return nil
+}
+
+// marker returns the marker of the summary comment reviewing pr.
+func (e *Engine) marker(pr *scm.PullRequest) Marker {
+	return Marker{PromptType: e.cfg.Review.PromptType, BaseSHA: pr.BaseSHA, HeadSHA: pr.HeadSHA}
+}
+
+// findSummaryComments returns the comments of author carrying a marker for
+// the given prompt type, oldest first.
+func findSummaryComments(comments []*scm.IssueComment, promptType, author string) []*scm.IssueComment {
+	var found []*scm.IssueComment
+	for _, comment := range comments {
+		if comment.Body == nil || comment.Author != author {
+			continue
+		}
+
+		marker, ok := ParseMarker(*comment.Body)
+		if ok && marker.PromptType == promptType {
+			found = append(found, comment)
+		}
+	}
+	return found
+}
@@ -0,0 +1,263 @@
+package reviewer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/reviewer"
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSticky_ParseMarker(t *testing.T) {
+	t.Run("Success_RoundTrip", func(t *testing.T) {
+		marker := reviewer.Marker{PromptType: "security"}
+
+		parsed, ok := reviewer.ParseMarker("Some text\n" + marker.String() + "\nReview")
+
+		assert.True(t, ok)
+		assert.Equal(t, marker, parsed)
+	})
+
+	t.Run("Success_RoundTripWithHeadSHA", func(t *testing.T) {
+		marker := reviewer.Marker{PromptType: "general", HeadSHA: "abc123"}
+
+		parsed, ok := reviewer.ParseMarker(marker.String() + "\nReview")
+
+		assert.True(t, ok)
+		assert.Equal(t, "<!-- elgtm:review prompt_type=general head_sha=abc123 -->", marker.String())
+		assert.Equal(t, marker, parsed)
+	})
+
+	t.Run("Success_RoundTripWithBaseSHA", func(t *testing.T) {
+		marker := reviewer.Marker{PromptType: "general", BaseSHA: "base000", HeadSHA: "abc123"}
+
+		parsed, ok := reviewer.ParseMarker(marker.String() + "\nReview")
+
+		assert.True(t, ok)
+		assert.Equal(t, "<!-- elgtm:review prompt_type=general base_sha=base000 head_sha=abc123 -->", marker.String())
+		assert.Equal(t, marker, parsed)
+	})
+
+	t.Run("Success_IgnoreUnknownAttributes", func(t *testing.T) {
+		parsed, ok := reviewer.ParseMarker("<!-- elgtm:review prompt_type=general future=1 -->")
+
+		assert.True(t, ok)
+		assert.Equal(t, "general", parsed.PromptType)
+	})
+
+	t.Run("Failure_NoMarker", func(t *testing.T) {
+		_, ok := reviewer.ParseMarker("<!-- some other comment -->\nLooks Good To Me!")
+
+		assert.False(t, ok)
+	})
+}
+
+func TestSticky_CommentStrategy(t *testing.T) {
+	newEngine := func(t *testing.T, strategy config.CommentStrategy) (*reviewer.Engine, *MockSCMClient, *MockLLMClient) {
+		t.Helper()
+
+		tempDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(tempDir, "general.md"), []byte("Hello! This is PR {{ .Number }}"), 0644)
+		require.NoError(t, err)
+
+		cfg := config.Config{
+			SCM: config.SCM{
+				Owner:    "owner",
+				Repo:     "repo",
+				PRNumber: 123,
+			},
+			Review: config.Review{
+				PromptType:      "general",
+				PromptDir:       tempDir,
+				CommentStrategy: strategy,
+			},
+		}
+
+		mockSCMClient := new(MockSCMClient)
+		mockLLMClient := new(MockLLMClient)
+
+		mockSCMClient.On("GetPullRequest", mock.Anything, "owner", "repo", 123).
+			Return(&scm.PullRequest{Number: 123}, nil)
+		mockLLMClient.On("GenerateContent", mock.Anything, mock.Anything).
+			Return("Looks Good To Me!", nil)
+
+		return reviewer.NewEngine(cfg, mockSCMClient, mockLLMClient), mockSCMClient, mockLLMClient
+	}
+
+	comment := func(id int64, body string) *scm.IssueComment {
+		return &scm.IssueComment{ID: id, Body: &body, Author: "elgtm-bot"}
+	}
+
+	expectedBody := "<!-- elgtm:review prompt_type=general -->\nLooks Good To Me!"
+
+	existing := []*scm.IssueComment{
+		comment(1, "<!-- elgtm:review prompt_type=general -->\nOld review"),
+		comment(2, "A human comment"),
+		comment(3, "<!-- elgtm:review prompt_type=security -->\nSecurity review"),
+		comment(4, "<!-- elgtm:review prompt_type=general -->\nNewer review"),
+	}
+
+	t.Run("Success_UpdateLatestComment", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return c.ID == 4 && *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "PostIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_UpdateFallsBackToPost", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing[1:3], nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_ReplaceDeletesThenPosts", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyReplace)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("DeleteIssueComment", mock.Anything, "owner", "repo", 123, int64(1)).Return(nil).Once()
+		mockSCMClient.On("DeleteIssueComment", mock.Anything, "owner", "repo", 123, int64(4)).Return(nil).Once()
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_IgnoreMarkerQuotedByOthers", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyReplace)
+
+		quoted := "> <!-- elgtm:review prompt_type=general -->\n> Newer review\n\nI disagree."
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).
+			Return([]*scm.IssueComment{
+				comment(1, "<!-- elgtm:review prompt_type=general -->\nOld review"),
+				{ID: 2, Body: &quoted, Author: "mallory"},
+			}, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("DeleteIssueComment", mock.Anything, "owner", "repo", 123, int64(1)).Return(nil).Once()
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "DeleteIssueComment", mock.Anything, "owner", "repo", 123, int64(2))
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Success_AppendAlwaysPosts", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyAppend)
+
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "ListIssueComments", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToListComments", func(t *testing.T) {
+		engine, mockSCMClient, _ := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(nil, fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list issue comments")
+	})
+
+	t.Run("Success_PostWhenCurrentUserUnknown", func(t *testing.T) {
+		engine, mockSCMClient, mockLLMClient := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).
+			Return("", fmt.Errorf("failed to get current user using SCM driver: %w", scm.ErrCurrentUserUnknown))
+		mockSCMClient.On("PostIssueComment", mock.Anything, "owner", "repo", 123, mock.MatchedBy(func(c *scm.IssueComment) bool {
+			return *c.Body == expectedBody
+		})).Return(nil)
+
+		err := engine.Run(context.Background())
+
+		assert.NoError(t, err)
+		mockSCMClient.AssertExpectations(t)
+		mockSCMClient.AssertNotCalled(t, "UpdateIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockLLMClient.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToGetCurrentUser", func(t *testing.T) {
+		engine, mockSCMClient, _ := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("", fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get current user")
+		mockSCMClient.AssertNotCalled(t, "UpdateIssueComment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure_FailedToUpdateComment", func(t *testing.T) {
+		engine, mockSCMClient, _ := newEngine(t, config.CommentStrategyUpdate)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("UpdateIssueComment", mock.Anything, "owner", "repo", 123, mock.Anything).Return(fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update issue comment")
+	})
+
+	t.Run("Failure_FailedToDeleteComment", func(t *testing.T) {
+		engine, mockSCMClient, _ := newEngine(t, config.CommentStrategyReplace)
+
+		mockSCMClient.On("ListIssueComments", mock.Anything, "owner", "repo", 123).Return(existing, nil)
+		mockSCMClient.On("GetCurrentUser", mock.Anything).Return("elgtm-bot", nil)
+		mockSCMClient.On("DeleteIssueComment", mock.Anything, "owner", "repo", 123, mock.Anything).Return(fmt.Errorf("boom"))
+
+		err := engine.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete issue comment")
+	})
+}
@@ -0,0 +1,594 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+)
+
+const (
+	// DefaultAzureDevOpsBaseURL is the URL of Azure DevOps Services.
+	DefaultAzureDevOpsBaseURL = "https://dev.azure.com"
+
+	azureDevOpsAPIVersion = "7.1"
+	// azureDevOpsPageSize is the number of changes requested per page.
+	azureDevOpsPageSize = 1000
+	// azureDevOpsDiffContext is the number of unchanged lines kept around the
+	// changes of the reconstructed diffs, as git does by default.
+	azureDevOpsDiffContext = 3
+	// azureDevOpsMaxFileSize is the size above which the content of a file is
+	// not fetched, and the file is listed in the diff without its changes.
+	azureDevOpsMaxFileSize = 1 << 20
+)
+
+// AzureDevOpsDriver talks to the Git API of Azure DevOps. Its repositories
+// belong to a project of an organization, so the owner of the requests is not
+// used.
+type AzureDevOpsDriver struct {
+	restClient
+	organizationURL string
+	projectURL      string
+}
+
+// NewAzureDevOpsDriver creates a driver for the project of the organization,
+// authenticated with a personal access token. baseURL defaults to
+// DefaultAzureDevOpsBaseURL and points to the server for Azure DevOps Server.
+func NewAzureDevOpsDriver(httpClient *http.Client, baseURL, organization, project, token string) (*AzureDevOpsDriver, error) {
+	if token == "" {
+		return nil, fmt.Errorf("azure devops token is missing")
+	}
+	if organization == "" || project == "" {
+		return nil, fmt.Errorf("azure devops organization and project are required")
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultAzureDevOpsBaseURL
+	}
+
+	authorize := func(req *http.Request) {
+		req.SetBasicAuth("", token)
+	}
+
+	organizationURL := strings.TrimSuffix(baseURL, "/") + "/" + url.PathEscape(organization)
+
+	return &AzureDevOpsDriver{
+		restClient:      restClient{httpClient: httpClient, authorize: authorize},
+		organizationURL: organizationURL,
+		projectURL:      organizationURL + "/" + url.PathEscape(project),
+	}, nil
+}
+
+type azureIdentity struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName"`
+}
+
+type azureCommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+type azurePullRequest struct {
+	PullRequestID int           `json:"pullRequestId"`
+	Title         string        `json:"title"`
+	Description   string        `json:"description"`
+	CreatedBy     azureIdentity `json:"createdBy"`
+	CreationDate  time.Time     `json:"creationDate"`
+	URL           string        `json:"url"`
+	Repository    struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository"`
+}
+
+// azureIteration is a push to the pull request. The changes of an iteration
+// are relative to the merge base of its source and target, CommonRefCommit.
+type azureIteration struct {
+	ID              int            `json:"id"`
+	SourceRefCommit azureCommitRef `json:"sourceRefCommit"`
+	CommonRefCommit azureCommitRef `json:"commonRefCommit"`
+	UpdatedDate     time.Time      `json:"updatedDate"`
+}
+
+type azureChange struct {
+	Item struct {
+		ObjectID         string `json:"objectId"`
+		OriginalObjectID string `json:"originalObjectId"`
+		Path             string `json:"path"`
+		GitObjectType    string `json:"gitObjectType"`
+		IsFolder         bool   `json:"isFolder"`
+	} `json:"item"`
+	// ChangeType lists the kinds of the change, e.g. "edit, rename".
+	ChangeType       string `json:"changeType"`
+	OriginalPath     string `json:"originalPath"`
+	SourceServerItem string `json:"sourceServerItem"`
+	// ChangeTrackingID identifies the file across the iterations of the pull
+	// request.
+	ChangeTrackingID int `json:"changeTrackingId"`
+}
+
+// azureLineEndOffset is the offset, past the end of any line, ending the
+// range of a thread so that it covers its last line in full.
+const azureLineEndOffset = 1 << 16
+
+// azureThreadPosition is a position in a file. The offset is the column of
+// the character, starting at 1.
+type azureThreadPosition struct {
+	Line   int `json:"line"`
+	Offset int `json:"offset"`
+}
+
+type azureThreadContext struct {
+	FilePath       string               `json:"filePath"`
+	LeftFileStart  *azureThreadPosition `json:"leftFileStart,omitempty"`
+	LeftFileEnd    *azureThreadPosition `json:"leftFileEnd,omitempty"`
+	RightFileStart *azureThreadPosition `json:"rightFileStart,omitempty"`
+	RightFileEnd   *azureThreadPosition `json:"rightFileEnd,omitempty"`
+}
+
+// azurePullRequestThreadContext ties a thread to the iteration, and to the file
+// of that iteration, its lines belong to, so that Azure DevOps keeps the thread
+// on them after later pushes.
+type azurePullRequestThreadContext struct {
+	ChangeTrackingID int                   `json:"changeTrackingId,omitempty"`
+	IterationContext azureIterationContext `json:"iterationContext"`
+}
+
+type azureIterationContext struct {
+	FirstComparingIteration  int `json:"firstComparingIteration"`
+	SecondComparingIteration int `json:"secondComparingIteration"`
+}
+
+type azureComment struct {
+	ID          int64          `json:"id,omitempty"`
+	Content     string         `json:"content"`
+	CommentType string         `json:"commentType,omitempty"`
+	Author      *azureIdentity `json:"author,omitempty"`
+	IsDeleted   bool           `json:"isDeleted,omitempty"`
+}
+
+type azureThread struct {
+	ID            int64               `json:"id,omitempty"`
+	Comments      []azureComment      `json:"comments"`
+	Status        string              `json:"status,omitempty"`
+	ThreadContext *azureThreadContext `json:"threadContext,omitempty"`
+	// PullRequestThreadContext is set along with ThreadContext.
+	PullRequestThreadContext *azurePullRequestThreadContext `json:"pullRequestThreadContext,omitempty"`
+	IsDeleted                bool                           `json:"isDeleted,omitempty"`
+}
+
+func (d *AzureDevOpsDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*GetPRResponse, error) {
+	var pr azurePullRequest
+	if err := d.request(ctx, "get pull request", http.MethodGet, d.pullRequestURL(req.Repo, req.Number, "", nil), nil, &pr); err != nil {
+		return nil, err
+	}
+
+	iteration, err := d.iteration(ctx, req.Repo, req.Number, "")
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := d.iterationChanges(ctx, req.Repo, req.Number, iteration.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rawDiff, files, err := d.unifiedDiff(ctx, req.Repo, changes, req.MaxDiffSize)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlURL := ""
+	if pr.Repository.WebURL != "" {
+		htmlURL = fmt.Sprintf("%s/pullrequest/%d", pr.Repository.WebURL, pr.PullRequestID)
+	}
+
+	return &GetPRResponse{
+		PR: &PullRequest{
+			ID:        int64(pr.PullRequestID),
+			Number:    pr.PullRequestID,
+			Title:     pr.Title,
+			Body:      pr.Description,
+			Author:    pr.CreatedBy.UniqueName,
+			URL:       pr.URL,
+			HTMLURL:   htmlURL,
+			RawDiff:   rawDiff,
+			Files:     files,
+			BaseSHA:   iteration.CommonRefCommit.CommitID,
+			HeadSHA:   iteration.SourceRefCommit.CommitID,
+			CreatedAt: pr.CreationDate,
+			UpdatedAt: iteration.UpdatedDate,
+		},
+	}, nil
+}
+
+// iteration returns the iteration of the pull request whose source is the
+// commit headSHA, or the latest one when there is none or headSHA is empty.
+func (d *AzureDevOpsDriver) iteration(ctx context.Context, repo string, number int, headSHA string) (*azureIteration, error) {
+	var iterations struct {
+		Value []azureIteration `json:"value"`
+	}
+	if err := d.request(ctx, "list pull request iterations", http.MethodGet, d.pullRequestURL(repo, number, "/iterations", nil), nil, &iterations); err != nil {
+		return nil, err
+	}
+	if len(iterations.Value) == 0 {
+		return nil, fmt.Errorf("pull request #%d has no iterations", number)
+	}
+
+	for i := range iterations.Value {
+		if headSHA != "" && iterations.Value[i].SourceRefCommit.CommitID == headSHA {
+			return &iterations.Value[i], nil
+		}
+	}
+	return &iterations.Value[len(iterations.Value)-1], nil
+}
+
+// iterationChanges returns every change of the iteration.
+func (d *AzureDevOpsDriver) iterationChanges(ctx context.Context, repo string, number, iteration int) ([]azureChange, error) {
+	var changes []azureChange
+
+	skip := 0
+	for {
+		query := url.Values{"$top": {strconv.Itoa(azureDevOpsPageSize)}, "$skip": {strconv.Itoa(skip)}}
+		var page struct {
+			ChangeEntries []azureChange `json:"changeEntries"`
+			NextSkip      int           `json:"nextSkip"`
+		}
+		if err := d.request(ctx, "list pull request changes", http.MethodGet, d.pullRequestURL(repo, number, fmt.Sprintf("/iterations/%d/changes", iteration), query), nil, &page); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, page.ChangeEntries...)
+
+		if page.NextSkip == 0 {
+			return changes, nil
+		}
+		skip = page.NextSkip
+	}
+}
+
+// unifiedDiff reconstructs the unified diff of the changes from the contents
+// of the files before and after them, since Azure DevOps has no endpoint
+// returning one. Files are fetched until the diff reaches maxDiffSize, and a
+// file whose content exceeds azureDevOpsMaxFileSize or what is left of
+// maxDiffSize is listed without its changes.
+func (d *AzureDevOpsDriver) unifiedDiff(ctx context.Context, repo string, changes []azureChange, maxDiffSize int64) (string, []ChangedFile, error) {
+	var b strings.Builder
+
+	for _, change := range changes {
+		if int64(b.Len()) >= maxDiffSize {
+			break
+		}
+
+		item := change.Item
+		if item.IsFolder || (item.GitObjectType != "" && item.GitObjectType != "blob") {
+			continue
+		}
+
+		file := diff.File{
+			NewPath:   strings.TrimPrefix(item.Path, "/"),
+			IsNew:     strings.Contains(change.ChangeType, "add"),
+			IsDeleted: strings.Contains(change.ChangeType, "delete"),
+			IsRenamed: strings.Contains(change.ChangeType, "rename"),
+		}
+
+		file.OldPath = file.NewPath
+		if file.IsRenamed {
+			oldPath := change.OriginalPath
+			if oldPath == "" {
+				oldPath = change.SourceServerItem
+			}
+			file.OldPath = strings.TrimPrefix(oldPath, "/")
+		}
+
+		// Deleted files may only carry the object ID of their last content.
+		oldID, newID := item.OriginalObjectID, item.ObjectID
+		switch {
+		case file.IsNew:
+			oldID = ""
+		case file.IsDeleted:
+			if oldID == "" {
+				oldID = newID
+			}
+			newID = ""
+		}
+
+		// A file renamed without edits keeps its object ID and has no hunks.
+		if oldID != newID {
+			limit := min(azureDevOpsMaxFileSize, maxDiffSize-int64(b.Len()))
+			oldContent, oldComplete, err := d.blob(ctx, repo, oldID, limit)
+			if err != nil {
+				return "", nil, err
+			}
+			newContent, newComplete, err := d.blob(ctx, repo, newID, limit)
+			if err != nil {
+				return "", nil, err
+			}
+
+			switch {
+			case !oldComplete || !newComplete:
+				// Too large to diff: the file is listed without its changes.
+			case bytes.IndexByte(oldContent, 0) >= 0 || bytes.IndexByte(newContent, 0) >= 0:
+				file.IsBinary = true
+			default:
+				file.Hunks = diff.Compute(string(oldContent), string(newContent), azureDevOpsDiffContext)
+			}
+		}
+
+		b.WriteString(diff.Format([]diff.File{file}))
+	}
+
+	rawDiff, files := truncateDiff(b.String(), maxDiffSize)
+	return rawDiff, files, nil
+}
+
+// blob returns the content of the blob with the object ID, which is empty
+// without an ID, and reports whether it was read in full: no more than limit
+// bytes are read.
+func (d *AzureDevOpsDriver) blob(ctx context.Context, repo, objectID string, limit int64) ([]byte, bool, error) {
+	if objectID == "" {
+		return nil, true, nil
+	}
+
+	query := url.Values{"$format": {"octetstream"}}
+	res, err := d.send(ctx, "get blob "+objectID, http.MethodGet, d.repositoryURL(repo, "/blobs/"+url.PathEscape(objectID), query), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read blob %s: %w", objectID, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, false, nil
+	}
+
+	return content, true, nil
+}
+
+func (d *AzureDevOpsDriver) PostIssueComment(ctx context.Context, req PostIssueCommentRequest) error {
+	return d.postThread(ctx, "post issue comment", req.Repo, req.Number, newAzureThread(*req.IssueComment.Body))
+}
+
+// ListIssueComments returns the general threads of the pull request as
+// comments, each made of the first comment of the thread and identified by the
+// thread. Threads on files and system threads, such as votes, are left out.
+func (d *AzureDevOpsDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	var threads struct {
+		Value []azureThread `json:"value"`
+	}
+	if err := d.request(ctx, "list issue comments", http.MethodGet, d.pullRequestURL(req.Repo, req.Number, "/threads", nil), nil, &threads); err != nil {
+		return nil, err
+	}
+
+	var comments []*IssueComment
+	for _, thread := range threads.Value {
+		if thread.ThreadContext != nil || thread.IsDeleted || len(thread.Comments) == 0 {
+			continue
+		}
+
+		first := thread.Comments[0]
+		if first.IsDeleted || first.CommentType == "system" {
+			continue
+		}
+
+		comment := &IssueComment{
+			ID:   thread.ID,
+			Body: &first.Content,
+		}
+		if first.Author != nil {
+			comment.Author = first.Author.ID
+		}
+		comments = append(comments, comment)
+	}
+
+	return &ListIssueCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+// UpdateIssueComment edits the first comment of the thread identified by the
+// ID of the comment.
+func (d *AzureDevOpsDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	comment := azureComment{Content: *req.IssueComment.Body}
+	op := fmt.Sprintf("update issue comment %d", req.IssueComment.ID)
+	return d.request(ctx, op, http.MethodPatch, d.pullRequestURL(req.Repo, req.Number, fmt.Sprintf("/threads/%d/comments/1", req.IssueComment.ID), nil), comment, nil)
+}
+
+// DeleteIssueComment deletes the first comment of the thread identified by the
+// ID of the comment, which deletes the thread.
+func (d *AzureDevOpsDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	op := fmt.Sprintf("delete issue comment %d", req.CommentID)
+	return d.request(ctx, op, http.MethodDelete, d.pullRequestURL(req.Repo, req.Number, fmt.Sprintf("/threads/%d/comments/1", req.CommentID), nil), nil, nil)
+}
+
+// PostReview posts the review body as a general thread and each comment as a
+// thread on its lines, since Azure DevOps has no reviews grouping them. The
+// threads are tied to the iteration of the reviewed commit, compared with the
+// first one as the diff of the whole pull request is.
+func (d *AzureDevOpsDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	var iteration *azureIteration
+	trackingIDs := make(map[string]int)
+	if len(req.Review.Comments) > 0 {
+		var err error
+		iteration, err = d.iteration(ctx, req.Repo, req.Number, req.Review.CommitID)
+		if err != nil {
+			return err
+		}
+
+		changes, err := d.iterationChanges(ctx, req.Repo, req.Number, iteration.ID)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			trackingIDs[change.Item.Path] = change.ChangeTrackingID
+		}
+	}
+
+	posted := false
+	if req.Review.Body != nil && *req.Review.Body != "" {
+		if err := d.postThread(ctx, "post review comment", req.Repo, req.Number, newAzureThread(*req.Review.Body)); err != nil {
+			return err
+		}
+		posted = true
+	}
+
+	var errs []error
+	for _, comment := range req.Review.Comments {
+		trackingID, ok := trackingIDs["/"+comment.Path]
+		if !ok {
+			trackingID = trackingIDs["/"+comment.OldPath]
+		}
+
+		thread := newAzureThread(comment.Body)
+		thread.ThreadContext = azureThreadContextOf(comment)
+		thread.PullRequestThreadContext = &azurePullRequestThreadContext{
+			ChangeTrackingID: trackingID,
+			IterationContext: azureIterationContext{FirstComparingIteration: 1, SecondComparingIteration: iteration.ID},
+		}
+
+		op := fmt.Sprintf("post comment thread on %s:%d", comment.Path, comment.Line(comment.End))
+		if err := d.postThread(ctx, op, req.Repo, req.Number, thread); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		posted = true
+	}
+
+	if len(errs) > 0 && posted {
+		return fmt.Errorf("%w: %w", errPartiallyPosted, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// azureThreadContextOf anchors a comment to its lines in the new version of
+// the file, the right side, or in the old version for removed lines. The range
+// spans from the start of its first line to the end of its last one.
+func azureThreadContextOf(comment *ReviewComment) *azureThreadContext {
+	end := &azureThreadPosition{Line: comment.Line(comment.End), Offset: azureLineEndOffset}
+	start := &azureThreadPosition{Line: comment.Line(comment.End), Offset: 1}
+	if comment.Start != nil {
+		start = &azureThreadPosition{Line: comment.Line(*comment.Start), Offset: 1}
+	}
+
+	threadContext := &azureThreadContext{FilePath: "/" + comment.Path}
+	if comment.Side == SideLeft {
+		threadContext.FilePath = "/" + comment.OldPath
+		if comment.OldPath == "" {
+			threadContext.FilePath = "/" + comment.Path
+		}
+		threadContext.LeftFileStart, threadContext.LeftFileEnd = start, end
+	} else {
+		threadContext.RightFileStart, threadContext.RightFileEnd = start, end
+	}
+
+	return threadContext
+}
+
+// newAzureThread returns an active thread made of a single comment.
+func newAzureThread(content string) azureThread {
+	return azureThread{
+		Comments: []azureComment{{Content: content, CommentType: "text"}},
+		Status:   "active",
+	}
+}
+
+func (d *AzureDevOpsDriver) postThread(ctx context.Context, op, repo string, number int, thread azureThread) error {
+	return d.request(ctx, op, http.MethodPost, d.pullRequestURL(repo, number, "/threads", nil), thread, nil)
+}
+
+// CompareCommits returns the diff between two commits of the repository,
+// reconstructed like the diff of a pull request. The base must be an ancestor
+// of the head; otherwise the history was rewritten and ErrCommitUnreachable is
+// returned.
+func (d *AzureDevOpsDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	var changes []azureChange
+
+	skip := 0
+	for {
+		query := url.Values{
+			"baseVersion":       {req.Base},
+			"baseVersionType":   {"commit"},
+			"targetVersion":     {req.Head},
+			"targetVersionType": {"commit"},
+			"diffCommonCommit":  {"true"},
+			"$top":              {strconv.Itoa(azureDevOpsPageSize)},
+			"$skip":             {strconv.Itoa(skip)},
+		}
+		var page struct {
+			CommonCommit string        `json:"commonCommit"`
+			Changes      []azureChange `json:"changes"`
+		}
+		err := d.request(ctx, "compare commits", http.MethodGet, d.repositoryURL(req.Repo, "/diffs/commits", query), nil, &page)
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, ErrCommitUnreachable)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, err)
+		}
+
+		if page.CommonCommit != req.Base {
+			return nil, fmt.Errorf("failed to compare %s...%s with merge base %s: %w", req.Base, req.Head, page.CommonCommit, ErrCommitUnreachable)
+		}
+
+		changes = append(changes, page.Changes...)
+
+		if len(page.Changes) < azureDevOpsPageSize {
+			break
+		}
+		skip += len(page.Changes)
+	}
+
+	rawDiff, files, err := d.unifiedDiff(ctx, req.Repo, changes, req.MaxDiffSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff for %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	return &CompareCommitsResponse{
+		Comparison: &Comparison{
+			BaseSHA: req.Base,
+			HeadSHA: req.Head,
+			RawDiff: rawDiff,
+			Files:   files,
+		},
+	}, nil
+}
+
+// GetCurrentUser returns the ID of the identity the token belongs to, which
+// is the ID of the author of its comments.
+func (d *AzureDevOpsDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	var connection struct {
+		AuthenticatedUser azureIdentity `json:"authenticatedUser"`
+	}
+	query := url.Values{"api-version": {azureDevOpsAPIVersion + "-preview"}}
+	if err := d.request(ctx, "get current user", http.MethodGet, d.organizationURL+"/_apis/connectionData?"+query.Encode(), nil, &connection); err != nil {
+		return nil, err
+	}
+
+	return &GetCurrentUserResponse{Author: connection.AuthenticatedUser.ID}, nil
+}
+
+func (d *AzureDevOpsDriver) repositoryURL(repo, path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("api-version", azureDevOpsAPIVersion)
+
+	return fmt.Sprintf("%s/_apis/git/repositories/%s%s?%s", d.projectURL, url.PathEscape(repo), path, query.Encode())
+}
+
+func (d *AzureDevOpsDriver) pullRequestURL(repo string, number int, path string, query url.Values) string {
+	return d.repositoryURL(repo, fmt.Sprintf("/pullRequests/%d%s", number, path), query)
+}
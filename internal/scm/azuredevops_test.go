@@ -0,0 +1,444 @@
+package scm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const azureRepoPath = "/contoso/Fabrikam/_apis/git/repositories/repo"
+
+// azureBlobs are the contents of the files changed by the pull request.
+var azureBlobs = map[string]string{
+	"main-old":   "package main\n\nfunc main() {\n\tprintln(\"old\")\n}\n",
+	"main-new":   "package main\n\nfunc main() {\n\tprintln(\"new\")\n}\n",
+	"added":      "package util\n",
+	"deleted":    "obsolete\n",
+	"logo":       "\x89PNG\x00\x01",
+	"renamed":    "unchanged\n",
+	"edited-old": "a\nb\n",
+	"edited-new": "a\nc\n",
+}
+
+// azureChanges are the changes of the last iteration of the pull request.
+const azureChanges = `[
+	{"changeType": "edit", "item": {"path": "/main.go", "objectId": "main-new", "originalObjectId": "main-old", "gitObjectType": "blob"}},
+	{"changeType": "add", "item": {"path": "/util/util.go", "objectId": "added", "gitObjectType": "blob"}},
+	{"changeType": "delete", "item": {"path": "/old.txt", "objectId": "deleted", "gitObjectType": "blob"}},
+	{"changeType": "add", "item": {"path": "/util", "isFolder": true, "gitObjectType": "tree"}},
+	{"changeType": "edit", "item": {"path": "/logo.png", "objectId": "logo", "originalObjectId": "main-old", "gitObjectType": "blob"}},
+	{"changeType": "rename", "originalPath": "/before.txt", "item": {"path": "/after.txt", "objectId": "renamed", "originalObjectId": "renamed", "gitObjectType": "blob"}},
+	{"changeType": "edit, rename", "originalPath": "/a.txt", "item": {"path": "/b.txt", "objectId": "edited-new", "originalObjectId": "edited-old", "gitObjectType": "blob"}}
+]`
+
+const azureDiff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,5 +1,5 @@\n package main\n \n func main() {\n-\tprintln(\"old\")\n+\tprintln(\"new\")\n }\n" +
+	"diff --git a/util/util.go b/util/util.go\n--- /dev/null\n+++ b/util/util.go\n@@ -0,0 +1,1 @@\n+package util\n" +
+	"diff --git a/old.txt b/old.txt\n--- a/old.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-obsolete\n" +
+	"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n" +
+	"diff --git a/before.txt b/after.txt\nrename from before.txt\nrename to after.txt\n" +
+	"diff --git a/a.txt b/b.txt\nrename from a.txt\nrename to b.txt\n--- a/a.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"
+
+// newAzureMux returns a mux checking that every request carries the personal
+// access token and the API version.
+func newAzureMux(t *testing.T) (*http.ServeMux, http.Handler) {
+	t.Helper()
+	mux := http.NewServeMux()
+	return mux, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "" || password != "fake-pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Regexp(t, `^7\.1(-preview)?$`, r.URL.Query().Get("api-version"))
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func handleAzureBlobs(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+azureRepoPath+"/blobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := azureBlobs[r.PathValue("id")]
+		if !ok || r.URL.Query().Get("$format") != "octetstream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, content)
+	})
+}
+
+func newAzureDriver(t *testing.T, handler http.Handler) *scm.AzureDevOpsDriver {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	driver, err := scm.NewAzureDevOpsDriver(&http.Client{Timeout: 5 * time.Second}, server.URL, "contoso", "Fabrikam", "fake-pat")
+	require.NoError(t, err)
+	return driver
+}
+
+func TestAzureDevOpsDriver_NewAzureDevOpsDriver(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := scm.NewAzureDevOpsDriver(httpClient, "", "contoso", "Fabrikam", "fake-pat")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingToken", func(t *testing.T) {
+		driver, err := scm.NewAzureDevOpsDriver(httpClient, "", "contoso", "Fabrikam", "")
+
+		assert.EqualError(t, err, "azure devops token is missing")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_MissingProject", func(t *testing.T) {
+		driver, err := scm.NewAzureDevOpsDriver(httpClient, "", "contoso", "", "fake-pat")
+
+		assert.EqualError(t, err, "azure devops organization and project are required")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestAzureDevOpsDriver_GetPullRequest(t *testing.T) {
+	ctx := context.Background()
+	req := scm.GetPRRequest{Owner: "ignored", Repo: "repo", Number: 7, MaxDiffSize: 4096}
+
+	mux, handler := newAzureMux(t)
+	mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"pullRequestId": 7,
+			"title": "feat: azure",
+			"description": "Adds a driver",
+			"createdBy": {"displayName": "Fauzil", "uniqueName": "fzl@example.com"},
+			"creationDate": "2025-01-02T03:04:05Z",
+			"repository": {"webUrl": "https://dev.azure.com/contoso/Fabrikam/_git/repo"},
+			"lastMergeSourceCommit": {"commitId": "merge-preview"}
+		}`)
+	})
+	mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/iterations", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"count": 2, "value": [
+			{"id": 1, "sourceRefCommit": {"commitId": "oldhead"}, "commonRefCommit": {"commitId": "base"}},
+			{"id": 2, "sourceRefCommit": {"commitId": "head"}, "commonRefCommit": {"commitId": "base"}, "updatedDate": "2025-01-03T00:00:00Z"}
+		]}`)
+	})
+	mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/iterations/2/changes", func(w http.ResponseWriter, r *http.Request) {
+		// Serve the changes over two pages to exercise the pagination.
+		if r.URL.Query().Get("$skip") == "0" {
+			fmt.Fprintf(w, `{"changeEntries": %s, "nextSkip": 7, "nextTop": 1000}`, azureChanges)
+			return
+		}
+		fmt.Fprint(w, `{"changeEntries": []}`)
+	})
+	handleAzureBlobs(mux)
+
+	driver := newAzureDriver(t, handler)
+
+	t.Run("Success_ReconstructUnifiedDiff", func(t *testing.T) {
+		res, err := driver.GetPullRequest(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, 7, res.PR.Number)
+		assert.Equal(t, "feat: azure", res.PR.Title)
+		assert.Equal(t, "Adds a driver", res.PR.Body)
+		assert.Equal(t, "fzl@example.com", res.PR.Author)
+		assert.Equal(t, "https://dev.azure.com/contoso/Fabrikam/_git/repo/pullrequest/7", res.PR.HTMLURL)
+		assert.Equal(t, "base", res.PR.BaseSHA)
+		assert.Equal(t, "head", res.PR.HeadSHA)
+		assert.Equal(t, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), res.PR.UpdatedAt)
+		assert.Equal(t, azureDiff, res.PR.RawDiff)
+
+		var paths []string
+		for _, file := range res.PR.Files {
+			paths = append(paths, file.Path())
+		}
+		assert.Equal(t, []string{"main.go", "util/util.go", "old.txt", "logo.png", "after.txt", "b.txt"}, paths)
+	})
+
+	t.Run("Success_TruncateDiff", func(t *testing.T) {
+		truncated := req
+		truncated.MaxDiffSize = 50
+
+		res, err := driver.GetPullRequest(ctx, truncated)
+
+		require.NoError(t, err)
+		assert.Equal(t, azureDiff[:50]+"\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ...", res.PR.RawDiff)
+	})
+
+	t.Run("Success_ListFilesOverBudgetWithoutChanges", func(t *testing.T) {
+		small := req
+		small.MaxDiffSize = 40
+
+		res, err := driver.GetPullRequest(ctx, small)
+
+		require.NoError(t, err)
+		assert.Equal(t, "diff --git a/main.go b/main.go\ndiff --gi\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ...", res.PR.RawDiff)
+	})
+
+	t.Run("Failure_Unauthorized", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		driver, err := scm.NewAzureDevOpsDriver(&http.Client{Timeout: 5 * time.Second}, server.URL, "contoso", "Fabrikam", "wrong-pat")
+		require.NoError(t, err)
+
+		_, err = driver.GetPullRequest(ctx, req)
+
+		var statusErr *scm.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+		assert.Equal(t, "get pull request", statusErr.Op)
+	})
+}
+
+func TestAzureDevOpsDriver_PostReview(t *testing.T) {
+	ctx := context.Background()
+	body := "Summary"
+
+	handleIterations := func(mux *http.ServeMux) {
+		mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/iterations", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"count": 3, "value": [
+				{"id": 1, "sourceRefCommit": {"commitId": "oldhead"}},
+				{"id": 2, "sourceRefCommit": {"commitId": "head"}},
+				{"id": 3, "sourceRefCommit": {"commitId": "newerhead"}}
+			]}`)
+		})
+		mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/iterations/2/changes", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"changeEntries": [
+				{"changeTrackingId": 1, "changeType": "edit", "item": {"path": "/main.go"}},
+				{"changeTrackingId": 2, "changeType": "edit", "item": {"path": "/util.go"}},
+				{"changeTrackingId": 3, "changeType": "delete", "item": {"path": "/old.go"}}
+			]}`)
+		})
+	}
+
+	t.Run("Success_PostThreads", func(t *testing.T) {
+		var threads []map[string]any
+
+		mux, handler := newAzureMux(t)
+		handleIterations(mux)
+		mux.HandleFunc("POST "+azureRepoPath+"/pullRequests/7/threads", func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			threads = append(threads, payload)
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+
+		err := newAzureDriver(t, handler).PostReview(ctx, scm.PostReviewRequest{
+			Repo:   "repo",
+			Number: 7,
+			Review: &scm.Review{
+				CommitID: "head",
+				Body:     &body,
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+					{Path: "util.go", Side: scm.SideRight, Start: &scm.DiffLine{NewLine: 3}, End: scm.DiffLine{NewLine: 5}, Body: "Multi line"},
+					{Path: "new.go", OldPath: "old.go", Side: scm.SideLeft, End: scm.DiffLine{OldLine: 7}, Body: "Removed line"},
+				},
+			},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, threads, 4)
+
+		assert.Equal(t, []any{map[string]any{"content": "Summary", "commentType": "text"}}, threads[0]["comments"])
+		assert.Equal(t, "active", threads[0]["status"])
+		assert.NotContains(t, threads[0], "threadContext")
+		assert.NotContains(t, threads[0], "pullRequestThreadContext")
+
+		start := func(line int) map[string]any {
+			return map[string]any{"line": float64(line), "offset": float64(1)}
+		}
+		end := func(line int) map[string]any {
+			return map[string]any{"line": float64(line), "offset": float64(1 << 16)}
+		}
+		assert.Equal(t, map[string]any{"filePath": "/main.go", "rightFileStart": start(10), "rightFileEnd": end(10)}, threads[1]["threadContext"])
+		assert.Equal(t, map[string]any{"filePath": "/util.go", "rightFileStart": start(3), "rightFileEnd": end(5)}, threads[2]["threadContext"])
+		assert.Equal(t, map[string]any{"filePath": "/old.go", "leftFileStart": start(7), "leftFileEnd": end(7)}, threads[3]["threadContext"])
+
+		pullRequestThreadContext := func(trackingID int) map[string]any {
+			return map[string]any{
+				"changeTrackingId": float64(trackingID),
+				"iterationContext": map[string]any{"firstComparingIteration": float64(1), "secondComparingIteration": float64(2)},
+			}
+		}
+		assert.Equal(t, pullRequestThreadContext(1), threads[1]["pullRequestThreadContext"])
+		assert.Equal(t, pullRequestThreadContext(2), threads[2]["pullRequestThreadContext"])
+		assert.Equal(t, pullRequestThreadContext(3), threads[3]["pullRequestThreadContext"])
+	})
+
+	t.Run("Failure_FailedToListIterations", func(t *testing.T) {
+		posted := false
+
+		mux, handler := newAzureMux(t)
+		mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/iterations", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		mux.HandleFunc("POST "+azureRepoPath+"/pullRequests/7/threads", func(w http.ResponseWriter, r *http.Request) {
+			posted = true
+		})
+
+		err := newAzureDriver(t, handler).PostReview(ctx, scm.PostReviewRequest{
+			Repo:   "repo",
+			Number: 7,
+			Review: &scm.Review{
+				Body:     &body,
+				Comments: []*scm.ReviewComment{{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"}},
+			},
+		})
+
+		assert.EqualError(t, err, "failed to list pull request iterations with status: 500")
+		assert.False(t, posted)
+	})
+
+	t.Run("Failure_PartiallyPosted", func(t *testing.T) {
+		mux, handler := newAzureMux(t)
+		handleIterations(mux)
+		mux.HandleFunc("POST "+azureRepoPath+"/pullRequests/7/threads", func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			if payload["threadContext"] != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+
+		err := newAzureDriver(t, handler).PostReview(ctx, scm.PostReviewRequest{
+			Repo:   "repo",
+			Number: 7,
+			Review: &scm.Review{
+				CommitID: "head",
+				Body:     &body,
+				Comments: []*scm.ReviewComment{{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"}},
+			},
+		})
+
+		assert.ErrorContains(t, err, "review was partially posted")
+		assert.ErrorContains(t, err, "failed to post comment thread on main.go:10 with status: 400")
+	})
+}
+
+func TestAzureDevOpsDriver_IssueComments(t *testing.T) {
+	ctx := context.Background()
+	body := "updated"
+
+	mux, handler := newAzureMux(t)
+	mux.HandleFunc("GET "+azureRepoPath+"/pullRequests/7/threads", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"value": [
+			{"id": 1, "comments": [{"id": 1, "content": "summary", "commentType": "text", "author": {"id": "8c6ea1f4", "uniqueName": "bot@example.com"}}]},
+			{"id": 2, "comments": [{"id": 1, "content": "inline", "commentType": "text"}], "threadContext": {"filePath": "/main.go"}},
+			{"id": 3, "comments": [{"id": 1, "content": "Fauzil voted 10", "commentType": "system"}]},
+			{"id": 4, "isDeleted": true, "comments": [{"id": 1, "content": "deleted", "commentType": "text"}]},
+			{"id": 5, "comments": [{"id": 1, "content": "second", "commentType": "text"}, {"id": 2, "content": "reply", "commentType": "text"}]}
+		]}`)
+	})
+	mux.HandleFunc("PATCH "+azureRepoPath+"/pullRequests/7/threads/5/comments/1", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, "updated", payload["content"])
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+	mux.HandleFunc("DELETE "+azureRepoPath+"/pullRequests/7/threads/5/comments/1", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST "+azureRepoPath+"/pullRequests/7/threads", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 6}`)
+	})
+	mux.HandleFunc("GET /contoso/_apis/connectionData", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"authenticatedUser": {"id": "8c6ea1f4", "providerDisplayName": "Review Bot"}}`)
+	})
+
+	driver := newAzureDriver(t, handler)
+
+	t.Run("Success_ListGeneralThreads", func(t *testing.T) {
+		res, err := driver.ListIssueComments(ctx, scm.ListIssueCommentsRequest{Repo: "repo", Number: 7})
+
+		require.NoError(t, err)
+		require.Len(t, res.Comments, 2)
+		assert.Equal(t, int64(1), res.Comments[0].ID)
+		assert.Equal(t, "summary", *res.Comments[0].Body)
+		assert.Equal(t, "8c6ea1f4", res.Comments[0].Author)
+		assert.Equal(t, int64(5), res.Comments[1].ID)
+		assert.Equal(t, "second", *res.Comments[1].Body)
+	})
+
+	t.Run("Success_GetCurrentUser", func(t *testing.T) {
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "8c6ea1f4", res.Author)
+	})
+
+	t.Run("Success_PostUpdateAndDelete", func(t *testing.T) {
+		assert.NoError(t, driver.PostIssueComment(ctx, scm.PostIssueCommentRequest{Repo: "repo", Number: 7, IssueComment: &scm.IssueComment{Body: &body}}))
+		assert.NoError(t, driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Repo: "repo", Number: 7, IssueComment: &scm.IssueComment{ID: 5, Body: &body}}))
+		assert.NoError(t, driver.DeleteIssueComment(ctx, scm.DeleteIssueCommentRequest{Repo: "repo", Number: 7, CommentID: 5}))
+	})
+
+	t.Run("Failure_UpdateMissingThread", func(t *testing.T) {
+		err := driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Repo: "repo", Number: 7, IssueComment: &scm.IssueComment{ID: 42, Body: &body}})
+
+		assert.EqualError(t, err, "failed to update issue comment 42 with status: 404")
+	})
+}
+
+func TestAzureDevOpsDriver_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+	req := scm.CompareCommitsRequest{Repo: "repo", Base: "base", Head: "head", MaxDiffSize: 4096}
+
+	newDriver := func(t *testing.T, commonCommit string, status int) *scm.AzureDevOpsDriver {
+		t.Helper()
+		mux, handler := newAzureMux(t)
+		mux.HandleFunc("GET "+azureRepoPath+"/diffs/commits", func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			assert.Equal(t, "base", query.Get("baseVersion"))
+			assert.Equal(t, "head", query.Get("targetVersion"))
+			assert.Equal(t, "true", query.Get("diffCommonCommit"))
+
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			fmt.Fprintf(w, `{"commonCommit": %q, "changes": [
+				{"changeType": "edit", "item": {"path": "/main.go", "objectId": "main-new", "originalObjectId": "main-old", "gitObjectType": "blob"}}
+			]}`, commonCommit)
+		})
+		handleAzureBlobs(mux)
+		return newAzureDriver(t, handler)
+	}
+
+	t.Run("Success_CompareCommits", func(t *testing.T) {
+		res, err := newDriver(t, "base", http.StatusOK).CompareCommits(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "base", res.Comparison.BaseSHA)
+		assert.Equal(t, "head", res.Comparison.HeadSHA)
+		assert.Contains(t, res.Comparison.RawDiff, "-\tprintln(\"old\")\n+\tprintln(\"new\")\n")
+		require.Len(t, res.Comparison.Files, 1)
+	})
+
+	t.Run("Failure_BaseNotAncestor", func(t *testing.T) {
+		_, err := newDriver(t, "other", http.StatusOK).CompareCommits(ctx, req)
+
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_BaseNotFound", func(t *testing.T) {
+		_, err := newDriver(t, "", http.StatusNotFound).CompareCommits(ctx, req)
+
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_FailedToCompare", func(t *testing.T) {
+		_, err := newDriver(t, "", http.StatusInternalServerError).CompareCommits(ctx, req)
+
+		assert.EqualError(t, err, "failed to compare base...head: failed to compare commits with status: 500")
+	})
+}
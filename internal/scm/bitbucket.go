@@ -0,0 +1,314 @@
+package scm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBitbucketBaseURL is the API of Bitbucket Cloud.
+const DefaultBitbucketBaseURL = "https://api.bitbucket.org/2.0"
+
+// BitbucketDriver talks to the pull request API of Bitbucket Cloud. The owner
+// of a repository is its workspace and the repository name its slug.
+type BitbucketDriver struct {
+	restClient
+	baseURL string
+}
+
+// NewBitbucketDriver creates a Bitbucket Cloud driver. It authenticates with
+// the app password of username when username is set, and with token as an
+// access token otherwise. baseURL defaults to DefaultBitbucketBaseURL.
+func NewBitbucketDriver(httpClient *http.Client, baseURL, username, token string) (*BitbucketDriver, error) {
+	if token == "" {
+		return nil, fmt.Errorf("bitbucket token is missing")
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultBitbucketBaseURL
+	}
+
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if username != "" {
+		authorize = func(req *http.Request) {
+			req.SetBasicAuth(username, token)
+		}
+	}
+
+	return &BitbucketDriver{
+		restClient: restClient{httpClient: httpClient, authorize: authorize},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+type bitbucketUser struct {
+	UUID        string `json:"uuid"`
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+type bitbucketContent struct {
+	Raw string `json:"raw"`
+}
+
+type bitbucketLink struct {
+	Href string `json:"href"`
+}
+
+type bitbucketPullRequest struct {
+	ID          int           `json:"id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Author      bitbucketUser `json:"author"`
+	Links       struct {
+		Self bitbucketLink `json:"self"`
+		HTML bitbucketLink `json:"html"`
+		Diff bitbucketLink `json:"diff"`
+	} `json:"links"`
+	Source      bitbucketEndpoint `json:"source"`
+	Destination bitbucketEndpoint `json:"destination"`
+	CreatedOn   time.Time         `json:"created_on"`
+	UpdatedOn   time.Time         `json:"updated_on"`
+}
+
+type bitbucketEndpoint struct {
+	Commit struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+}
+
+type bitbucketInline struct {
+	Path      string `json:"path"`
+	From      *int   `json:"from,omitempty"`
+	To        *int   `json:"to,omitempty"`
+	StartFrom *int   `json:"start_from,omitempty"`
+	StartTo   *int   `json:"start_to,omitempty"`
+}
+
+type bitbucketComment struct {
+	ID      int64            `json:"id,omitempty"`
+	Content bitbucketContent `json:"content"`
+	User    *bitbucketUser   `json:"user,omitempty"`
+	Inline  *bitbucketInline `json:"inline,omitempty"`
+	Deleted bool             `json:"deleted,omitempty"`
+}
+
+func (d *BitbucketDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*GetPRResponse, error) {
+	var pr bitbucketPullRequest
+	if err := d.request(ctx, "get pull request", http.MethodGet, d.pullRequestURL(req.Owner, req.Repo, req.Number, ""), nil, &pr); err != nil {
+		return nil, err
+	}
+
+	rawDiff, files, err := d.diff(ctx, d.pullRequestURL(req.Owner, req.Repo, req.Number, "/diff"), req.MaxDiffSize)
+	if err != nil {
+		return nil, err
+	}
+
+	author := pr.Author.Nickname
+	if author == "" {
+		author = pr.Author.DisplayName
+	}
+
+	return &GetPRResponse{
+		PR: &PullRequest{
+			ID:        int64(pr.ID),
+			Number:    pr.ID,
+			Title:     pr.Title,
+			Body:      pr.Description,
+			Author:    author,
+			URL:       pr.Links.Self.Href,
+			HTMLURL:   pr.Links.HTML.Href,
+			DiffURL:   pr.Links.Diff.Href,
+			RawDiff:   rawDiff,
+			Files:     files,
+			BaseSHA:   pr.Destination.Commit.Hash,
+			HeadSHA:   pr.Source.Commit.Hash,
+			CreatedAt: pr.CreatedOn,
+			UpdatedAt: pr.UpdatedOn,
+		},
+	}, nil
+}
+
+func (d *BitbucketDriver) PostIssueComment(ctx context.Context, req PostIssueCommentRequest) error {
+	comment := bitbucketComment{Content: bitbucketContent{Raw: *req.IssueComment.Body}}
+	return d.request(ctx, "post issue comment", http.MethodPost, d.pullRequestURL(req.Owner, req.Repo, req.Number, "/comments"), comment, nil)
+}
+
+// ListIssueComments returns the comments on the pull request itself, leaving
+// out inline and deleted comments.
+func (d *BitbucketDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	var comments []*IssueComment
+
+	next := d.pullRequestURL(req.Owner, req.Repo, req.Number, "/comments?pagelen=100")
+	for next != "" {
+		var page struct {
+			Values []bitbucketComment `json:"values"`
+			Next   string             `json:"next"`
+		}
+		if err := d.request(ctx, "list issue comments", http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+
+		for _, comment := range page.Values {
+			if comment.Inline != nil || comment.Deleted {
+				continue
+			}
+
+			issueComment := &IssueComment{
+				ID:   comment.ID,
+				Body: &comment.Content.Raw,
+			}
+			if comment.User != nil {
+				issueComment.Author = comment.User.UUID
+			}
+			comments = append(comments, issueComment)
+		}
+
+		next = page.Next
+	}
+
+	return &ListIssueCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+func (d *BitbucketDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	comment := bitbucketComment{Content: bitbucketContent{Raw: *req.IssueComment.Body}}
+	path := fmt.Sprintf("/comments/%d", req.IssueComment.ID)
+	op := fmt.Sprintf("update issue comment %d", req.IssueComment.ID)
+	return d.request(ctx, op, http.MethodPut, d.pullRequestURL(req.Owner, req.Repo, req.Number, path), comment, nil)
+}
+
+func (d *BitbucketDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	path := fmt.Sprintf("/comments/%d", req.CommentID)
+	op := fmt.Sprintf("delete issue comment %d", req.CommentID)
+	return d.request(ctx, op, http.MethodDelete, d.pullRequestURL(req.Owner, req.Repo, req.Number, path), nil, nil)
+}
+
+// PostReview posts the review body as a comment and each comment as an inline
+// comment, since Bitbucket has no reviews grouping them.
+func (d *BitbucketDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	commentsURL := d.pullRequestURL(req.Owner, req.Repo, req.Number, "/comments")
+
+	posted := false
+	if req.Review.Body != nil && *req.Review.Body != "" {
+		comment := bitbucketComment{Content: bitbucketContent{Raw: *req.Review.Body}}
+		if err := d.request(ctx, "post review comment", http.MethodPost, commentsURL, comment, nil); err != nil {
+			return err
+		}
+		posted = true
+	}
+
+	var errs []error
+	for _, comment := range req.Review.Comments {
+		inline := bitbucketComment{
+			Content: bitbucketContent{Raw: comment.Body},
+			Inline:  bitbucketInlinePosition(comment),
+		}
+		op := fmt.Sprintf("post inline comment on %s:%d", comment.Path, comment.Line(comment.End))
+		if err := d.request(ctx, op, http.MethodPost, commentsURL, inline, nil); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		posted = true
+	}
+
+	if len(errs) > 0 && posted {
+		return fmt.Errorf("%w: %w", errPartiallyPosted, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// bitbucketInlinePosition anchors a comment to a line of the new version of
+// the file with "to", or of the old version with "from" for removed lines.
+func bitbucketInlinePosition(comment *ReviewComment) *bitbucketInline {
+	inline := &bitbucketInline{Path: comment.Path}
+
+	line := comment.Line(comment.End)
+	if comment.Side == SideLeft {
+		inline.From = &line
+	} else {
+		inline.To = &line
+	}
+
+	if comment.Start != nil {
+		start := comment.Line(*comment.Start)
+		if comment.Side == SideLeft {
+			inline.StartFrom = &start
+		} else {
+			inline.StartTo = &start
+		}
+	}
+
+	return inline
+}
+
+// CompareCommits returns the diff between two commits of the repository. The
+// base must be an ancestor of the head; otherwise the history was rewritten
+// and ErrCommitUnreachable is returned.
+func (d *BitbucketDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	var mergeBase struct {
+		Hash string `json:"hash"`
+	}
+	err := d.request(ctx, "find merge base", http.MethodGet, d.repositoryURL(req.Owner, req.Repo, "/merge-base/"+url.PathEscape(req.Base+".."+req.Head)), nil, &mergeBase)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", req.Base, req.Head, ErrCommitUnreachable)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", req.Base, req.Head, err)
+	}
+
+	// Pull requests carry abbreviated hashes while the merge base is in full.
+	if !strings.HasPrefix(mergeBase.Hash, req.Base) && !strings.HasPrefix(req.Base, mergeBase.Hash) {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, ErrCommitUnreachable)
+	}
+
+	// A spec of head..base diffs head against its merge base with base.
+	rawDiff, files, err := d.diff(ctx, d.repositoryURL(req.Owner, req.Repo, "/diff/"+url.PathEscape(req.Head+".."+req.Base)), req.MaxDiffSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	return &CompareCommitsResponse{
+		Comparison: &Comparison{
+			BaseSHA: req.Base,
+			HeadSHA: req.Head,
+			RawDiff: rawDiff,
+			Files:   files,
+		},
+	}, nil
+}
+
+// GetCurrentUser returns the UUID of the user the credentials belong to, since
+// nicknames are not unique. Repository, project and workspace access tokens are
+// denied the endpoint, in which case ErrCurrentUserUnknown is returned.
+func (d *BitbucketDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	var user bitbucketUser
+	err := d.request(ctx, "get current user", http.MethodGet, d.baseURL+"/user", nil, &user)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+		return nil, fmt.Errorf("%w: %w", err, ErrCurrentUserUnknown)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetCurrentUserResponse{Author: user.UUID}, nil
+}
+
+func (d *BitbucketDriver) repositoryURL(owner, repo, path string) string {
+	return fmt.Sprintf("%s/repositories/%s/%s%s", d.baseURL, url.PathEscape(owner), url.PathEscape(repo), path)
+}
+
+func (d *BitbucketDriver) pullRequestURL(owner, repo string, number int, path string) string {
+	return d.repositoryURL(owner, repo, fmt.Sprintf("/pullrequests/%d%s", number, path))
+}
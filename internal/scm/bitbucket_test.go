@@ -0,0 +1,439 @@
+package scm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBitbucketDriver(t *testing.T, server *httptest.Server) *scm.BitbucketDriver {
+	t.Helper()
+	driver, err := scm.NewBitbucketDriver(&http.Client{Timeout: 5 * time.Second}, server.URL+"/2.0", "", "fake-token")
+	require.NoError(t, err)
+	return driver
+}
+
+func TestBitbucketDriver_NewBitbucketDriver(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := scm.NewBitbucketDriver(httpClient, "", "reviewer", "fake-app-password")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingToken", func(t *testing.T) {
+		driver, err := scm.NewBitbucketDriver(httpClient, "", "reviewer", "")
+
+		assert.EqualError(t, err, "bitbucket token is missing")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestBitbucketDriver_Authentication(t *testing.T) {
+	ctx := context.Background()
+	body := "LGTM"
+	req := scm.PostIssueCommentRequest{Owner: "workspace", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{Body: &body}}
+
+	newServer := func(t *testing.T, authorization *string) *httptest.Server {
+		t.Helper()
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /2.0/repositories/workspace/repo/pullrequests/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			*authorization = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("Success_AppPassword", func(t *testing.T) {
+		var authorization string
+		server := newServer(t, &authorization)
+
+		driver, err := scm.NewBitbucketDriver(&http.Client{Timeout: 5 * time.Second}, server.URL+"/2.0/", "reviewer", "app-password")
+		require.NoError(t, err)
+
+		require.NoError(t, driver.PostIssueComment(ctx, req))
+
+		r := &http.Request{Header: http.Header{"Authorization": {authorization}}}
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "reviewer", username)
+		assert.Equal(t, "app-password", password)
+	})
+
+	t.Run("Success_AccessToken", func(t *testing.T) {
+		var authorization string
+		server := newServer(t, &authorization)
+
+		require.NoError(t, newBitbucketDriver(t, server).PostIssueComment(ctx, req))
+
+		assert.Equal(t, "Bearer fake-token", authorization)
+	})
+}
+
+func TestBitbucketDriver_GetPullRequest(t *testing.T) {
+	ctx := context.Background()
+	rawDiff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2.0/repositories/workspace/repo/pullrequests/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"id": 1,
+			"title": "feat: bitbucket",
+			"description": "Adds a driver",
+			"author": {"display_name": "Fauzil", "nickname": "fzl-22"},
+			"links": {"html": {"href": "https://bitbucket.org/workspace/repo/pull-requests/1"}},
+			"source": {"commit": {"hash": "headsha12345"}},
+			"destination": {"commit": {"hash": "basesha12345"}},
+			"created_on": "2025-01-02T03:04:05.000000+00:00"
+		}`)
+	})
+	mux.HandleFunc("GET /2.0/repositories/workspace/repo/pullrequests/1/diff", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, rawDiff)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	driver := newBitbucketDriver(t, server)
+
+	t.Run("Success_GetPullRequest", func(t *testing.T) {
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{Owner: "workspace", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.PR.Number)
+		assert.Equal(t, "feat: bitbucket", res.PR.Title)
+		assert.Equal(t, "Adds a driver", res.PR.Body)
+		assert.Equal(t, "fzl-22", res.PR.Author)
+		assert.Equal(t, "https://bitbucket.org/workspace/repo/pull-requests/1", res.PR.HTMLURL)
+		assert.Equal(t, "basesha12345", res.PR.BaseSHA)
+		assert.Equal(t, "headsha12345", res.PR.HeadSHA)
+		assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), res.PR.CreatedAt.UTC())
+		assert.Equal(t, rawDiff, res.PR.RawDiff)
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "main.go", res.PR.Files[0].Path())
+	})
+
+	t.Run("Success_GetPullRequestWithTruncation", func(t *testing.T) {
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{Owner: "workspace", Repo: "repo", Number: 1, MaxDiffSize: 10})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(res.PR.RawDiff, rawDiff[:10]))
+		assert.Contains(t, res.PR.RawDiff, "DIFF TRUNCATED")
+	})
+
+	t.Run("Failure_PullRequestNotFound", func(t *testing.T) {
+		_, err := driver.GetPullRequest(ctx, scm.GetPRRequest{Owner: "workspace", Repo: "repo", Number: 2, MaxDiffSize: 1024})
+
+		var statusErr *scm.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+		assert.Equal(t, "get pull request", statusErr.Op)
+	})
+
+	t.Run("Failure_RateLimitedIsRetryable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		_, err := newBitbucketDriver(t, server).GetPullRequest(ctx, scm.GetPRRequest{Owner: "workspace", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		require.Error(t, err)
+		retryable, _ := scm.ClassifyError(err)
+		assert.True(t, retryable)
+	})
+}
+
+func TestBitbucketDriver_PostReview(t *testing.T) {
+	ctx := context.Background()
+	reviewBody := "Summary"
+
+	t.Run("Success_PostInlineComments", func(t *testing.T) {
+		var comments []map[string]any
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /2.0/repositories/workspace/repo/pullrequests/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			comments = append(comments, payload)
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		err := newBitbucketDriver(t, server).PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "workspace",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{
+				Body: &reviewBody,
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+					{Path: "util.go", Side: scm.SideRight, Start: &scm.DiffLine{OldLine: 3, NewLine: 4}, End: scm.DiffLine{NewLine: 5}, Body: "Multi line"},
+					{Path: "old.go", Side: scm.SideLeft, End: scm.DiffLine{OldLine: 7}, Body: "Removed line"},
+				},
+			},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, comments, 4)
+
+		assert.Equal(t, map[string]any{"raw": "Summary"}, comments[0]["content"])
+		assert.NotContains(t, comments[0], "inline")
+
+		assert.Equal(t, map[string]any{"raw": "Single line"}, comments[1]["content"])
+		assert.Equal(t, map[string]any{"path": "main.go", "to": float64(10)}, comments[1]["inline"])
+		assert.Equal(t, map[string]any{"path": "util.go", "to": float64(5), "start_to": float64(4)}, comments[2]["inline"])
+		assert.Equal(t, map[string]any{"path": "old.go", "from": float64(7)}, comments[3]["inline"])
+	})
+
+	t.Run("Failure_PartiallyPosted", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /2.0/repositories/workspace/repo/pullrequests/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			if payload["inline"] != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"type": "error", "error": {"message": "Invalid line"}}`)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		err := newBitbucketDriver(t, server).PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "workspace",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{
+				Body: &reviewBody,
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+				},
+			},
+		})
+
+		assert.ErrorContains(t, err, "review was partially posted")
+		assert.ErrorContains(t, err, "post inline comment on main.go:10")
+	})
+}
+
+func TestBitbucketDriver_ListIssueComments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_ListAllPagesSkippingInlineComments", func(t *testing.T) {
+		var server *httptest.Server
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /2.0/repositories/workspace/repo/pullrequests/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "2" {
+				fmt.Fprint(w, `{"values": [{"id": 3, "content": {"raw": "second"}, "user": {"uuid": "{bot}", "nickname": "bot"}}]}`)
+				return
+			}
+			assert.Equal(t, "100", r.URL.Query().Get("pagelen"))
+			fmt.Fprintf(w, `{"values": [
+				{"id": 1, "content": {"raw": "first"}, "user": {"uuid": "{bot}", "nickname": "bot"}},
+				{"id": 2, "content": {"raw": "inline"}, "inline": {"path": "main.go", "to": 1}},
+				{"id": 4, "content": {"raw": ""}, "deleted": true}
+			], "next": "%s/2.0/repositories/workspace/repo/pullrequests/1/comments?pagelen=100&page=2"}`, server.URL)
+		})
+		server = httptest.NewServer(mux)
+		defer server.Close()
+
+		res, err := newBitbucketDriver(t, server).ListIssueComments(ctx, scm.ListIssueCommentsRequest{Owner: "workspace", Repo: "repo", Number: 1})
+
+		require.NoError(t, err)
+		require.Len(t, res.Comments, 2)
+		assert.Equal(t, int64(1), res.Comments[0].ID)
+		assert.Equal(t, "first", *res.Comments[0].Body)
+		assert.Equal(t, "{bot}", res.Comments[0].Author)
+		assert.Equal(t, int64(3), res.Comments[1].ID)
+	})
+
+	t.Run("Failure_FailedToListComments", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		_, err := newBitbucketDriver(t, server).ListIssueComments(ctx, scm.ListIssueCommentsRequest{Owner: "workspace", Repo: "repo", Number: 1})
+
+		assert.ErrorContains(t, err, "list issue comments")
+	})
+}
+
+func TestBitbucketDriver_GetCurrentUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_GetCurrentUser", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /2.0/user", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"uuid": "{bot}", "nickname": "bot", "display_name": "Review Bot"}`)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		res, err := newBitbucketDriver(t, server).GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "{bot}", res.Author)
+	})
+
+	t.Run("Failure_FailedToGetCurrentUser", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		_, err := newBitbucketDriver(t, server).GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		assert.EqualError(t, err, "failed to get current user with status: 404")
+		assert.NotErrorIs(t, err, scm.ErrCurrentUserUnknown)
+	})
+
+	t.Run("Failure_AccessTokenDenied", func(t *testing.T) {
+		for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(code)
+			}))
+
+			_, err := newBitbucketDriver(t, server).GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+			server.Close()
+
+			assert.ErrorIs(t, err, scm.ErrCurrentUserUnknown, "status %d", code)
+		}
+	})
+}
+
+func TestBitbucketDriver_UpdateIssueComment(t *testing.T) {
+	ctx := context.Background()
+	body := "updated"
+
+	var payload map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /2.0/repositories/workspace/repo/pullrequests/1/comments/7", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		fmt.Fprint(w, `{"id": 7}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	driver := newBitbucketDriver(t, server)
+
+	t.Run("Success_UpdateComment", func(t *testing.T) {
+		err := driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Owner: "workspace", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{ID: 7, Body: &body}})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"raw": "updated"}, payload["content"])
+	})
+
+	t.Run("Failure_FailedToUpdateComment", func(t *testing.T) {
+		err := driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Owner: "workspace", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{ID: 8, Body: &body}})
+
+		assert.ErrorContains(t, err, "update issue comment 8")
+	})
+}
+
+func TestBitbucketDriver_DeleteIssueComment(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /2.0/repositories/workspace/repo/pullrequests/1/comments/7", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	driver := newBitbucketDriver(t, server)
+
+	t.Run("Success_DeleteComment", func(t *testing.T) {
+		assert.NoError(t, driver.DeleteIssueComment(ctx, scm.DeleteIssueCommentRequest{Owner: "workspace", Repo: "repo", Number: 1, CommentID: 7}))
+	})
+
+	t.Run("Failure_FailedToDeleteComment", func(t *testing.T) {
+		err := driver.DeleteIssueComment(ctx, scm.DeleteIssueCommentRequest{Owner: "workspace", Repo: "repo", Number: 1, CommentID: 8})
+
+		assert.ErrorContains(t, err, "delete issue comment 8")
+	})
+}
+
+func TestBitbucketDriver_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+	rawDiff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+	req := scm.CompareCommitsRequest{Owner: "workspace", Repo: "repo", Base: "basesha", Head: "headsha", MaxDiffSize: 1024}
+
+	newServer := func(t *testing.T, mergeBase func(w http.ResponseWriter)) *httptest.Server {
+		t.Helper()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /2.0/repositories/workspace/repo/merge-base/{spec}", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "basesha..headsha", r.PathValue("spec"))
+			mergeBase(w)
+		})
+		mux.HandleFunc("GET /2.0/repositories/workspace/repo/diff/{spec}", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "headsha..basesha", r.PathValue("spec"))
+			fmt.Fprint(w, rawDiff)
+		})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("Success_CompareCommits", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"hash": "basesha0123456789"}`)
+		})
+
+		res, err := newBitbucketDriver(t, server).CompareCommits(ctx, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "basesha", res.Comparison.BaseSHA)
+		assert.Equal(t, "headsha", res.Comparison.HeadSHA)
+		assert.Equal(t, rawDiff, res.Comparison.RawDiff)
+		require.Len(t, res.Comparison.Files, 1)
+	})
+
+	t.Run("Failure_BaseNotAncestor", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"hash": "othersha"}`)
+		})
+
+		_, err := newBitbucketDriver(t, server).CompareCommits(ctx, req)
+
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_BaseNotFound", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		_, err := newBitbucketDriver(t, server).CompareCommits(ctx, req)
+
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_FailedToFindMergeBase", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		_, err := newBitbucketDriver(t, server).CompareCommits(ctx, req)
+
+		assert.ErrorContains(t, err, "failed to find merge base of basesha and headsha")
+		assert.NotErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+}
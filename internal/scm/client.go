@@ -5,17 +5,20 @@ import (
 	"fmt"
 
 	"github.com/fzl-22/elgtm/internal/config"
+	"github.com/fzl-22/elgtm/internal/retry"
 )
 
 type client struct {
 	driver Driver
 	cfg    config.SCM
+	policy retry.Policy
 }
 
 func NewClient(driver Driver, cfg config.SCM) Client {
 	return &client{
 		driver: driver,
 		cfg:    cfg,
+		policy: retry.NewPolicy(cfg.Retry),
 	}
 }
 
@@ -23,12 +26,17 @@ func (c *client) GetPullRequest(ctx context.Context, owner, repo string, number
 	req := GetPRRequest{
 		Owner:       owner,
 		Repo:        repo,
-		Token:       c.cfg.Token,
+		Token:       c.cfg.Token.Value(),
 		Number:      number,
 		MaxDiffSize: c.cfg.MaxDiffSize,
 	}
 
-	resp, err := c.driver.GetPullRequest(ctx, req)
+	var resp *GetPRResponse
+	err := retry.Do(ctx, c.policy, "get pull request", ClassifyError, func() error {
+		var err error
+		resp, err = c.driver.GetPullRequest(ctx, req)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pull request using SCM driver: %w", err)
 	}
@@ -41,14 +49,135 @@ func (c *client) PostIssueComment(ctx context.Context, owner, repo string, numbe
 		Owner:        owner,
 		Repo:         repo,
 		Number:       number,
-		Token:        c.cfg.Token,
+		Token:        c.cfg.Token.Value(),
 		IssueComment: issueComment,
 	}
 
-	err := c.driver.PostIssueComment(ctx, req)
+	err := retry.Do(ctx, c.policy, "post issue comment", classifyRateLimit, func() error {
+		return c.driver.PostIssueComment(ctx, req)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to post issue comment using SCM driver: %w", err)
 	}
 
 	return nil
 }
+
+func (c *client) ListIssueComments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error) {
+	req := ListIssueCommentsRequest{
+		Owner:  owner,
+		Repo:   repo,
+		Number: number,
+		Token:  c.cfg.Token.Value(),
+	}
+
+	var resp *ListIssueCommentsResponse
+	err := retry.Do(ctx, c.policy, "list issue comments", ClassifyError, func() error {
+		var err error
+		resp, err = c.driver.ListIssueComments(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue comments using SCM driver: %w", err)
+	}
+
+	return resp.Comments, nil
+}
+
+func (c *client) UpdateIssueComment(ctx context.Context, owner, repo string, number int, issueComment *IssueComment) error {
+	req := UpdateIssueCommentRequest{
+		Owner:        owner,
+		Repo:         repo,
+		Number:       number,
+		Token:        c.cfg.Token.Value(),
+		IssueComment: issueComment,
+	}
+
+	err := retry.Do(ctx, c.policy, "update issue comment", ClassifyError, func() error {
+		return c.driver.UpdateIssueComment(ctx, req)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update issue comment using SCM driver: %w", err)
+	}
+
+	return nil
+}
+
+func (c *client) DeleteIssueComment(ctx context.Context, owner, repo string, number int, commentID int64) error {
+	req := DeleteIssueCommentRequest{
+		Owner:     owner,
+		Repo:      repo,
+		Number:    number,
+		Token:     c.cfg.Token.Value(),
+		CommentID: commentID,
+	}
+
+	err := retry.Do(ctx, c.policy, "delete issue comment", ClassifyError, func() error {
+		return c.driver.DeleteIssueComment(ctx, req)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete issue comment using SCM driver: %w", err)
+	}
+
+	return nil
+}
+
+func (c *client) PostReview(ctx context.Context, owner, repo string, number int, review *Review) error {
+	req := PostReviewRequest{
+		Owner:  owner,
+		Repo:   repo,
+		Number: number,
+		Token:  c.cfg.Token.Value(),
+		Review: review,
+	}
+
+	err := retry.Do(ctx, c.policy, "post review", classifyRateLimit, func() error {
+		return c.driver.PostReview(ctx, req)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post review using SCM driver: %w", err)
+	}
+
+	return nil
+}
+
+func (c *client) CompareCommits(ctx context.Context, owner, repo, base, head string) (*Comparison, error) {
+	req := CompareCommitsRequest{
+		Owner:       owner,
+		Repo:        repo,
+		Base:        base,
+		Head:        head,
+		Token:       c.cfg.Token.Value(),
+		MaxDiffSize: c.cfg.MaxDiffSize,
+	}
+
+	var resp *CompareCommitsResponse
+	err := retry.Do(ctx, c.policy, "compare commits", ClassifyError, func() error {
+		var err error
+		resp, err = c.driver.CompareCommits(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits using SCM driver: %w", err)
+	}
+
+	return resp.Comparison, nil
+}
+
+func (c *client) GetCurrentUser(ctx context.Context) (string, error) {
+	req := GetCurrentUserRequest{
+		Token: c.cfg.Token.Value(),
+	}
+
+	var resp *GetCurrentUserResponse
+	err := retry.Do(ctx, c.policy, "get current user", ClassifyError, func() error {
+		var err error
+		resp, err = c.driver.GetCurrentUser(ctx, req)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user using SCM driver: %w", err)
+	}
+
+	return resp.Author, nil
+}
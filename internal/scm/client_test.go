@@ -2,13 +2,16 @@ package scm_test
 
 import (
 	"context"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/fzl-22/elgtm/internal/config"
 	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/google/go-github/v82/github"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
 type MockDriver struct {
@@ -28,6 +31,45 @@ func (m *MockDriver) PostIssueComment(ctx context.Context, req scm.PostIssueComm
 	return args.Error(0)
 }
 
+func (m *MockDriver) ListIssueComments(ctx context.Context, req scm.ListIssueCommentsRequest) (*scm.ListIssueCommentsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*scm.ListIssueCommentsResponse), args.Error(1)
+}
+
+func (m *MockDriver) UpdateIssueComment(ctx context.Context, req scm.UpdateIssueCommentRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockDriver) DeleteIssueComment(ctx context.Context, req scm.DeleteIssueCommentRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockDriver) PostReview(ctx context.Context, req scm.PostReviewRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockDriver) CompareCommits(ctx context.Context, req scm.CompareCommitsRequest) (*scm.CompareCommitsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*scm.CompareCommitsResponse), args.Error(1)
+}
+
+func (m *MockDriver) GetCurrentUser(ctx context.Context, req scm.GetCurrentUserRequest) (*scm.GetCurrentUserResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*scm.GetCurrentUserResponse), args.Error(1)
+}
+
 func TestClient_NewClient(t *testing.T) {
 	t.Run("Success_InitClient", func(t *testing.T) {
 		mockDriver := new(MockDriver)
@@ -141,3 +183,333 @@ func TestClient_PostIssueComment(t *testing.T) {
 		mockDriver.AssertExpectations(t)
 	})
 }
+
+func TestClient_ListIssueComments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SuccessListIssueComments", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		commentBody := "Test comment!"
+		expected := []*scm.IssueComment{{ID: 1, Body: &commentBody, Author: "fzl-22"}}
+
+		mockDriver.On("ListIssueComments", mock.Anything, scm.ListIssueCommentsRequest{
+			Owner:  "fzl-22",
+			Repo:   "elgtm",
+			Number: 123,
+		}).Return(&scm.ListIssueCommentsResponse{Comments: expected}, nil)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		comments, err := client.ListIssueComments(ctx, "fzl-22", "elgtm", 123)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, comments)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToListIssueComments", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("ListIssueComments", mock.Anything, mock.Anything).
+			Return(nil, assert.AnError)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		comments, err := client.ListIssueComments(ctx, "fzl-22", "elgtm", 123)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list issue comments using SCM driver")
+		assert.Nil(t, comments)
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_UpdateIssueComment(t *testing.T) {
+	ctx := context.Background()
+
+	commentBody := "Test comment!"
+	issueComment := &scm.IssueComment{ID: 1, Body: &commentBody}
+
+	t.Run("Success_SuccessUpdateIssueComment", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("UpdateIssueComment", mock.Anything, scm.UpdateIssueCommentRequest{
+			Owner:        "fzl-22",
+			Repo:         "elgtm",
+			Number:       123,
+			IssueComment: issueComment,
+		}).Return(nil)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.UpdateIssueComment(ctx, "fzl-22", "elgtm", 123, issueComment)
+
+		assert.NoError(t, err)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToUpdateIssueComment", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("UpdateIssueComment", mock.Anything, mock.Anything).
+			Return(assert.AnError)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.UpdateIssueComment(ctx, "fzl-22", "elgtm", 123, issueComment)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update issue comment using SCM driver")
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_DeleteIssueComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SuccessDeleteIssueComment", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("DeleteIssueComment", mock.Anything, scm.DeleteIssueCommentRequest{
+			Owner:     "fzl-22",
+			Repo:      "elgtm",
+			Number:    123,
+			CommentID: 1,
+		}).Return(nil)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.DeleteIssueComment(ctx, "fzl-22", "elgtm", 123, 1)
+
+		assert.NoError(t, err)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToDeleteIssueComment", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("DeleteIssueComment", mock.Anything, mock.Anything).
+			Return(assert.AnError)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.DeleteIssueComment(ctx, "fzl-22", "elgtm", 123, 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete issue comment using SCM driver")
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_PostReview(t *testing.T) {
+	ctx := context.Background()
+
+	review := &scm.Review{
+		CommitID: "abc123",
+		Comments: []*scm.ReviewComment{
+			{Path: "main.go", OldPath: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Test comment!"},
+		},
+	}
+
+	t.Run("Success_SuccessPostReview", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("PostReview", mock.Anything, mock.MatchedBy(func(req scm.PostReviewRequest) bool {
+			return req.Owner == "fzl-22" && req.Repo == "elgtm" && req.Number == 123 && req.Review == review
+		})).Return(nil)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.PostReview(ctx, "fzl-22", "elgtm", 123, review)
+
+		assert.NoError(t, err)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToPostReview", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("PostReview", mock.Anything, mock.Anything).
+			Return(assert.AnError)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		err := client.PostReview(ctx, "fzl-22", "elgtm", 123, review)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to post review using SCM driver")
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SuccessCompareCommits", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		expected := &scm.Comparison{BaseSHA: "abc123", HeadSHA: "def456", RawDiff: "diff"}
+
+		mockDriver.On("CompareCommits", mock.Anything, scm.CompareCommitsRequest{
+			Owner:       "fzl-22",
+			Repo:        "elgtm",
+			Base:        "abc123",
+			Head:        "def456",
+			Token:       "token",
+			MaxDiffSize: 1024,
+		}).Return(&scm.CompareCommitsResponse{Comparison: expected}, nil)
+
+		client := scm.NewClient(mockDriver, config.SCM{Token: "token", MaxDiffSize: 1024})
+
+		comparison, err := client.CompareCommits(ctx, "fzl-22", "elgtm", "abc123", "def456")
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, comparison)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToCompareCommits", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("CompareCommits", mock.Anything, mock.Anything).
+			Return(nil, scm.ErrCommitUnreachable)
+
+		cfg := config.Config{}
+		client := scm.NewClient(mockDriver, cfg.SCM)
+
+		comparison, err := client.CompareCommits(ctx, "fzl-22", "elgtm", "abc123", "def456")
+
+		assert.Nil(t, comparison)
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+		assert.Contains(t, err.Error(), "failed to compare commits using SCM driver")
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_GetCurrentUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SuccessGetCurrentUser", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("GetCurrentUser", mock.Anything, scm.GetCurrentUserRequest{Token: "token"}).
+			Return(&scm.GetCurrentUserResponse{Author: "elgtm-bot"}, nil)
+
+		client := scm.NewClient(mockDriver, config.SCM{Token: "token"})
+
+		author, err := client.GetCurrentUser(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "elgtm-bot", author)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_FailedToGetCurrentUser", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("GetCurrentUser", mock.Anything, mock.Anything).
+			Return(nil, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "Bad credentials"})
+
+		client := scm.NewClient(mockDriver, config.SCM{})
+
+		author, err := client.GetCurrentUser(ctx)
+
+		assert.Empty(t, author)
+		assert.Contains(t, err.Error(), "failed to get current user using SCM driver")
+		mockDriver.AssertExpectations(t)
+	})
+}
+
+func TestClient_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := config.SCM{
+		Retry: config.Retry{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+
+	t.Run("Success_RetryServerError", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("GetPullRequest", mock.Anything, mock.Anything).
+			Return(nil, &scm.StatusError{Op: "get diff", StatusCode: http.StatusBadGateway}).Once()
+		mockDriver.On("GetPullRequest", mock.Anything, mock.Anything).
+			Return(&scm.GetPRResponse{PR: &scm.PullRequest{Number: 1}}, nil).Once()
+
+		client := scm.NewClient(mockDriver, cfg)
+
+		pr, err := client.GetPullRequest(ctx, "owner", "repo", 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, pr.Number)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Success_RetryRateLimitedComment", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("PostIssueComment", mock.Anything, mock.Anything).
+			Return(&scm.StatusError{Op: "post comment", StatusCode: http.StatusTooManyRequests, RetryAfter: time.Millisecond}).Once()
+		mockDriver.On("PostIssueComment", mock.Anything, mock.Anything).
+			Return(nil).Once()
+
+		client := scm.NewClient(mockDriver, cfg)
+
+		err := client.PostIssueComment(ctx, "owner", "repo", 1, &scm.IssueComment{})
+
+		assert.NoError(t, err)
+		mockDriver.AssertExpectations(t)
+	})
+
+	t.Run("Failure_DoNotRetryCommentOnServerError", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("PostIssueComment", mock.Anything, mock.Anything).
+			Return(&scm.StatusError{Op: "post comment", StatusCode: http.StatusBadGateway}).Once()
+
+		client := scm.NewClient(mockDriver, cfg)
+
+		err := client.PostIssueComment(ctx, "owner", "repo", 1, &scm.IssueComment{})
+
+		assert.Error(t, err)
+		mockDriver.AssertNumberOfCalls(t, "PostIssueComment", 1)
+	})
+
+	t.Run("Failure_DoNotRetryAuthError", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("ListIssueComments", mock.Anything, mock.Anything).
+			Return(nil, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}, Message: "Bad credentials"}).Once()
+
+		client := scm.NewClient(mockDriver, cfg)
+
+		comments, err := client.ListIssueComments(ctx, "owner", "repo", 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, comments)
+		mockDriver.AssertNumberOfCalls(t, "ListIssueComments", 1)
+	})
+
+	t.Run("Failure_OutOfAttempts", func(t *testing.T) {
+		mockDriver := new(MockDriver)
+
+		mockDriver.On("DeleteIssueComment", mock.Anything, mock.Anything).
+			Return(&gitlab.ErrorResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}})
+
+		client := scm.NewClient(mockDriver, cfg)
+
+		err := client.DeleteIssueComment(ctx, "owner", "repo", 1, 42)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete issue comment using SCM driver")
+		mockDriver.AssertNumberOfCalls(t, "DeleteIssueComment", 3)
+	})
+}
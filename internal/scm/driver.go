@@ -1,10 +1,31 @@
 package scm
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrCommitUnreachable is returned by CompareCommits when the base commit is
+// not an ancestor of the head commit, typically after a force-push.
+var ErrCommitUnreachable = errors.New("base commit is not reachable from head")
+
+// ErrCurrentUserUnknown is returned by GetCurrentUser when the credentials
+// cannot tell which user they belong to, as with Bitbucket access tokens.
+var ErrCurrentUserUnknown = errors.New("current user is unknown")
+
+// ErrCompareUnsupported is returned by CompareCommits on platforms whose API
+// cannot compare two commits.
+var ErrCompareUnsupported = errors.New("comparing commits is not supported")
 
 type Driver interface {
 	GetPullRequest(ctx context.Context, req GetPRRequest) (*GetPRResponse, error)
 	PostIssueComment(ctx context.Context, req PostIssueCommentRequest) error
+	ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error)
+	UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error
+	DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error
+	PostReview(ctx context.Context, req PostReviewRequest) error
+	CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error)
+	GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error)
 }
 
 type GetPRRequest struct {
@@ -26,3 +47,61 @@ type PostIssueCommentRequest struct {
 	IssueComment *IssueComment
 	Token        string
 }
+
+type ListIssueCommentsRequest struct {
+	Owner  string
+	Repo   string
+	Number int
+	Token  string
+}
+
+type ListIssueCommentsResponse struct {
+	Comments []*IssueComment
+}
+
+type UpdateIssueCommentRequest struct {
+	Owner        string
+	Repo         string
+	Number       int
+	IssueComment *IssueComment
+	Token        string
+}
+
+type DeleteIssueCommentRequest struct {
+	Owner     string
+	Repo      string
+	Number    int
+	CommentID int64
+	Token     string
+}
+
+type PostReviewRequest struct {
+	Owner  string
+	Repo   string
+	Number int
+	Review *Review
+	Token  string
+}
+
+type CompareCommitsRequest struct {
+	Owner       string
+	Repo        string
+	Base        string
+	Head        string
+	Token       string
+	MaxDiffSize int64
+}
+
+type CompareCommitsResponse struct {
+	Comparison *Comparison
+}
+
+type GetCurrentUserRequest struct {
+	Token string
+}
+
+type GetCurrentUserResponse struct {
+	// Author identifies the authenticated user the way IssueComment.Author
+	// identifies the author of a comment.
+	Author string
+}
@@ -0,0 +1,119 @@
+package scm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/retry"
+	"github.com/google/go-github/v82/github"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// secondaryRateLimitDelay is how long GitHub asks clients to wait after
+// hitting a secondary rate limit that came without a Retry-After header.
+const secondaryRateLimitDelay = time.Minute
+
+// errPartiallyPosted marks errors of calls that posted some of their comments
+// before failing, which must not be retried to avoid duplicate comments.
+var errPartiallyPosted = errors.New("review was partially posted")
+
+// StatusError is returned when a request the drivers make without an SDK
+// fails with an unexpected HTTP status.
+type StatusError struct {
+	// Op describes the request, e.g. "get diff".
+	Op         string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func newStatusError(op string, res *http.Response) *StatusError {
+	return &StatusError{
+		Op:         op,
+		StatusCode: res.StatusCode,
+		RetryAfter: retry.ParseRetryAfter(res.Header),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to %s with status: %d", e.Op, e.StatusCode)
+}
+
+// ClassifyError is the retry classifier of SCM calls. Rate limits, server
+// errors and network errors are retried, after the delay the platform asked
+// for if any; other client errors, authentication errors in particular, are
+// not.
+func ClassifyError(err error) (bool, time.Duration) {
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if after := abuseErr.GetRetryAfter(); after > 0 {
+			return true, after
+		}
+		return true, secondaryRateLimitDelay
+	}
+
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		return true, max(time.Until(rateErr.Rate.Reset.Time), 0)
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.StatusCode), statusErr.RetryAfter
+	}
+
+	if res := errorResponse(err); res != nil {
+		return isRetryableStatus(res.StatusCode), retry.ParseRetryAfter(res.Header)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr), 0
+}
+
+// classifyRateLimit is the retry classifier of calls that create something.
+// They are only retried when rate limited, since the platform may have acted
+// on a request that failed with a server or network error.
+func classifyRateLimit(err error) (bool, time.Duration) {
+	retryable, after := ClassifyError(err)
+	if !retryable || !isRateLimit(err) || errors.Is(err, errPartiallyPosted) {
+		return false, 0
+	}
+	return true, after
+}
+
+func isRateLimit(err error) bool {
+	var abuseErr *github.AbuseRateLimitError
+	var rateErr *github.RateLimitError
+	if errors.As(err, &abuseErr) || errors.As(err, &rateErr) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	res := errorResponse(err)
+	return res != nil && res.StatusCode == http.StatusTooManyRequests
+}
+
+// errorResponse returns the HTTP response of a GitHub or GitLab API error.
+func errorResponse(err error) *http.Response {
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) {
+		return githubErr.Response
+	}
+
+	var gitlabErr *gitlab.ErrorResponse
+	if errors.As(err, &gitlabErr) {
+		return gitlabErr.Response
+	}
+
+	return nil
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
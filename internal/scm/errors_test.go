@@ -0,0 +1,74 @@
+package scm_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestErrors_ClassifyError(t *testing.T) {
+	t.Run("Success_SecondaryRateLimit", func(t *testing.T) {
+		retryAfter := 30 * time.Second
+		err := fmt.Errorf("failed to post review: %w", &github.AbuseRateLimitError{RetryAfter: &retryAfter})
+
+		retryable, after := scm.ClassifyError(err)
+
+		assert.True(t, retryable)
+		assert.Equal(t, retryAfter, after)
+	})
+
+	t.Run("Success_SecondaryRateLimitWithoutRetryAfter", func(t *testing.T) {
+		retryable, after := scm.ClassifyError(&github.AbuseRateLimitError{})
+
+		assert.True(t, retryable)
+		assert.Equal(t, time.Minute, after)
+	})
+
+	t.Run("Success_PrimaryRateLimit", func(t *testing.T) {
+		reset := github.Timestamp{Time: time.Now().Add(time.Hour)}
+
+		retryable, after := scm.ClassifyError(&github.RateLimitError{Rate: github.Rate{Reset: reset}})
+
+		assert.True(t, retryable)
+		assert.Greater(t, after, 59*time.Minute)
+	})
+
+	t.Run("Success_ServerErrorWithRetryAfter", func(t *testing.T) {
+		res := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": []string{"5"}}}
+
+		retryable, after := scm.ClassifyError(&gitlab.ErrorResponse{Response: res})
+
+		assert.True(t, retryable)
+		assert.Equal(t, 5*time.Second, after)
+	})
+
+	t.Run("Success_DiffStatusError", func(t *testing.T) {
+		retryable, _ := scm.ClassifyError(&scm.StatusError{Op: "get diff", StatusCode: http.StatusBadGateway})
+
+		assert.True(t, retryable)
+	})
+
+	t.Run("Failure_AuthErrors", func(t *testing.T) {
+		for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+			retryable, _ := scm.ClassifyError(&github.ErrorResponse{Response: &http.Response{StatusCode: code}})
+			assert.False(t, retryable, "status %d", code)
+
+			retryable, _ = scm.ClassifyError(&gitlab.ErrorResponse{Response: &http.Response{StatusCode: code}})
+			assert.False(t, retryable, "status %d", code)
+		}
+	})
+
+	t.Run("Failure_UnrelatedError", func(t *testing.T) {
+		retryable, _ := scm.ClassifyError(scm.ErrCommitUnreachable)
+		assert.False(t, retryable)
+
+		retryable, _ = scm.ClassifyError(scm.ErrCompareUnsupported)
+		assert.False(t, retryable)
+	})
+}
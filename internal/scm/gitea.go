@@ -0,0 +1,200 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GiteaDriver talks to the API of a Gitea or Forgejo instance, which resembles
+// GitHub's without being compatible with its SDK.
+type GiteaDriver struct {
+	restClient
+	apiURL string
+}
+
+// NewGiteaDriver creates a driver for the Gitea or Forgejo instance at
+// baseURL, e.g. https://gitea.example.com, authenticated with an access token.
+func NewGiteaDriver(httpClient *http.Client, baseURL, token string) (*GiteaDriver, error) {
+	if token == "" {
+		return nil, fmt.Errorf("gitea token is missing")
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("gitea base URL is missing")
+	}
+
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	return &GiteaDriver{
+		restClient: restClient{httpClient: httpClient, authorize: authorize},
+		apiURL:     strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v1") + "/api/v1",
+	}, nil
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+type giteaPullRequest struct {
+	ID        int64     `json:"id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	User      giteaUser `json:"user"`
+	URL       string    `json:"url"`
+	HTMLURL   string    `json:"html_url"`
+	DiffURL   string    `json:"diff_url"`
+	Base      giteaRef  `json:"base"`
+	Head      giteaRef  `json:"head"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type giteaRef struct {
+	SHA string `json:"sha"`
+}
+
+type giteaComment struct {
+	ID   int64     `json:"id,omitempty"`
+	Body string    `json:"body"`
+	User giteaUser `json:"user,omitzero"`
+}
+
+type giteaReview struct {
+	Body     string               `json:"body,omitempty"`
+	Event    string               `json:"event"`
+	CommitID string               `json:"commit_id,omitempty"`
+	Comments []giteaReviewComment `json:"comments,omitempty"`
+}
+
+// giteaReviewComment anchors a comment to a line of the new version of the
+// file with NewPosition, or of the old version with OldPosition. Gitea has no
+// comments on ranges of lines.
+type giteaReviewComment struct {
+	Path        string `json:"path"`
+	Body        string `json:"body"`
+	NewPosition int    `json:"new_position,omitempty"`
+	OldPosition int    `json:"old_position,omitempty"`
+}
+
+func (d *GiteaDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*GetPRResponse, error) {
+	var pr giteaPullRequest
+	if err := d.request(ctx, "get pull request", http.MethodGet, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/pulls/%d", req.Number)), nil, &pr); err != nil {
+		return nil, err
+	}
+
+	// The diff_url of the pull request is a page of the web interface, which
+	// does not accept tokens for private repositories.
+	rawDiff, files, err := d.diff(ctx, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/pulls/%d.diff", req.Number)), req.MaxDiffSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetPRResponse{
+		PR: &PullRequest{
+			ID:        pr.ID,
+			Number:    pr.Number,
+			Title:     pr.Title,
+			Body:      pr.Body,
+			Author:    pr.User.Login,
+			URL:       pr.URL,
+			HTMLURL:   pr.HTMLURL,
+			DiffURL:   pr.DiffURL,
+			RawDiff:   rawDiff,
+			Files:     files,
+			BaseSHA:   pr.Base.SHA,
+			HeadSHA:   pr.Head.SHA,
+			CreatedAt: pr.CreatedAt,
+			UpdatedAt: pr.UpdatedAt,
+		},
+	}, nil
+}
+
+func (d *GiteaDriver) PostIssueComment(ctx context.Context, req PostIssueCommentRequest) error {
+	comment := giteaComment{Body: *req.IssueComment.Body}
+	return d.request(ctx, "post issue comment", http.MethodPost, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/issues/%d/comments", req.Number)), comment, nil)
+}
+
+// ListIssueComments returns every comment of the pull request at once, since
+// the endpoint is not paginated.
+func (d *GiteaDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	var page []giteaComment
+	if err := d.request(ctx, "list issue comments", http.MethodGet, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/issues/%d/comments", req.Number)), nil, &page); err != nil {
+		return nil, err
+	}
+
+	comments := make([]*IssueComment, 0, len(page))
+	for _, comment := range page {
+		comments = append(comments, &IssueComment{
+			ID:     comment.ID,
+			Body:   &comment.Body,
+			Author: comment.User.Login,
+		})
+	}
+
+	return &ListIssueCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+func (d *GiteaDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	comment := giteaComment{Body: *req.IssueComment.Body}
+	op := fmt.Sprintf("update issue comment %d", req.IssueComment.ID)
+	return d.request(ctx, op, http.MethodPatch, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/issues/comments/%d", req.IssueComment.ID)), comment, nil)
+}
+
+func (d *GiteaDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	op := fmt.Sprintf("delete issue comment %d", req.CommentID)
+	return d.request(ctx, op, http.MethodDelete, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/issues/comments/%d", req.CommentID)), nil, nil)
+}
+
+// PostReview submits the review with its inline comments at once. Comments on
+// a range of lines are anchored to the last line of the range.
+func (d *GiteaDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	review := giteaReview{
+		Event:    "COMMENT",
+		CommitID: req.Review.CommitID,
+		Comments: make([]giteaReviewComment, 0, len(req.Review.Comments)),
+	}
+	if req.Review.Body != nil {
+		review.Body = *req.Review.Body
+	}
+
+	for _, comment := range req.Review.Comments {
+		draft := giteaReviewComment{Path: comment.Path, Body: comment.Body}
+		if comment.Side == SideLeft {
+			draft.OldPosition = comment.Line(comment.End)
+		} else {
+			draft.NewPosition = comment.Line(comment.End)
+		}
+		review.Comments = append(review.Comments, draft)
+	}
+
+	return d.request(ctx, "create pull request review", http.MethodPost, d.repositoryURL(req.Owner, req.Repo, fmt.Sprintf("/pulls/%d/reviews", req.Number)), review, nil)
+}
+
+// CompareCommits always returns ErrCompareUnsupported, since the API of Gitea
+// has no diff between two commits: pull requests are reviewed in full on every
+// push.
+func (d *GiteaDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	return nil, fmt.Errorf("gitea cannot compare %s...%s: %w", req.Base, req.Head, ErrCompareUnsupported)
+}
+
+// GetCurrentUser returns the login of the user the token belongs to.
+func (d *GiteaDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	var user giteaUser
+	if err := d.request(ctx, "get current user", http.MethodGet, d.apiURL+"/user", nil, &user); err != nil {
+		return nil, err
+	}
+
+	return &GetCurrentUserResponse{Author: user.Login}, nil
+}
+
+func (d *GiteaDriver) repositoryURL(owner, repo, path string) string {
+	return fmt.Sprintf("%s/repos/%s/%s%s", d.apiURL, url.PathEscape(owner), url.PathEscape(repo), path)
+}
@@ -0,0 +1,308 @@
+package scm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// giteaStub emulates the endpoints of a Forgejo instance used by the driver,
+// keeping the comments of pull request 1 of owner/repo in memory.
+type giteaStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	nextID   int64
+	comments map[int64]string
+	reviews  []map[string]any
+}
+
+const giteaDiff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+
+func newGiteaStub(t *testing.T) *giteaStub {
+	t.Helper()
+	stub := &giteaStub{nextID: 1, comments: map[int64]string{}}
+
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token fake-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			next(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /forgejo/api/v1/repos/owner/repo/pulls/1", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{
+			"id": 12345,
+			"number": 1,
+			"title": "feat: forgejo",
+			"body": "Adds a driver",
+			"user": {"login": "fzl-22"},
+			"html_url": "%[1]s/forgejo/owner/repo/pulls/1",
+			"diff_url": "%[1]s/forgejo/owner/repo/pulls/1.diff",
+			"base": {"sha": "basesha"},
+			"head": {"sha": "headsha"},
+			"created_at": "2025-01-02T03:04:05Z"
+		}`, stub.URL)
+	}))
+	mux.HandleFunc("GET /forgejo/api/v1/repos/owner/repo/pulls/1.diff", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, giteaDiff)
+	}))
+	mux.HandleFunc("GET /forgejo/api/v1/user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 7, "login": "elgtm-bot"}`)
+	}))
+	mux.HandleFunc("GET /forgejo/api/v1/repos/owner/repo/issues/1/comments", authorized(func(w http.ResponseWriter, r *http.Request) {
+		comments := []map[string]any{}
+		for id := int64(1); id < stub.nextID; id++ {
+			if body, ok := stub.comments[id]; ok {
+				comments = append(comments, map[string]any{"id": id, "body": body, "user": map[string]any{"login": "elgtm-bot"}})
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(comments))
+	}))
+	mux.HandleFunc("POST /forgejo/api/v1/repos/owner/repo/issues/1/comments", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Body string `json:"body"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		id := stub.nextID
+		stub.nextID++
+		stub.comments[id] = payload.Body
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": %d}`, id)
+	}))
+	mux.HandleFunc("PATCH /forgejo/api/v1/repos/owner/repo/issues/comments/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		id := stub.commentID(w, r)
+		if id == 0 {
+			return
+		}
+
+		var payload struct {
+			Body string `json:"body"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		stub.comments[id] = payload.Body
+
+		fmt.Fprintf(w, `{"id": %d}`, id)
+	}))
+	mux.HandleFunc("DELETE /forgejo/api/v1/repos/owner/repo/issues/comments/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if id := stub.commentID(w, r); id != 0 {
+			delete(stub.comments, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	mux.HandleFunc("POST /forgejo/api/v1/repos/owner/repo/pulls/1/reviews", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if payload["event"] != "COMMENT" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		stub.reviews = append(stub.reviews, payload)
+
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+// commentID returns the existing comment of the path, or writes a 404 and
+// returns 0.
+func (s *giteaStub) commentID(w http.ResponseWriter, r *http.Request) int64 {
+	var id int64
+	fmt.Sscan(r.PathValue("id"), &id)
+	if _, ok := s.comments[id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return 0
+	}
+	return id
+}
+
+func (s *giteaStub) driver(t *testing.T, token string) *scm.GiteaDriver {
+	t.Helper()
+	driver, err := scm.NewGiteaDriver(&http.Client{Timeout: 5 * time.Second}, s.URL+"/forgejo/", token)
+	require.NoError(t, err)
+	return driver
+}
+
+func TestGiteaDriver_NewGiteaDriver(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		driver, err := scm.NewGiteaDriver(httpClient, "https://gitea.example.com", "fake-token")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingToken", func(t *testing.T) {
+		driver, err := scm.NewGiteaDriver(httpClient, "https://gitea.example.com", "")
+
+		assert.EqualError(t, err, "gitea token is missing")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_MissingBaseURL", func(t *testing.T) {
+		driver, err := scm.NewGiteaDriver(httpClient, "", "fake-token")
+
+		assert.EqualError(t, err, "gitea base URL is missing")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestGiteaDriver_GetPullRequest(t *testing.T) {
+	ctx := context.Background()
+	stub := newGiteaStub(t)
+
+	t.Run("Success_GetPullRequest", func(t *testing.T) {
+		res, err := stub.driver(t, "fake-token").GetPullRequest(ctx, scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(12345), res.PR.ID)
+		assert.Equal(t, 1, res.PR.Number)
+		assert.Equal(t, "feat: forgejo", res.PR.Title)
+		assert.Equal(t, "Adds a driver", res.PR.Body)
+		assert.Equal(t, "fzl-22", res.PR.Author)
+		assert.Equal(t, stub.URL+"/forgejo/owner/repo/pulls/1", res.PR.HTMLURL)
+		assert.Equal(t, "basesha", res.PR.BaseSHA)
+		assert.Equal(t, "headsha", res.PR.HeadSHA)
+		assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), res.PR.CreatedAt)
+		assert.Equal(t, giteaDiff, res.PR.RawDiff)
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "main.go", res.PR.Files[0].Path())
+	})
+
+	t.Run("Success_GetPullRequestWithTruncation", func(t *testing.T) {
+		res, err := stub.driver(t, "fake-token").GetPullRequest(ctx, scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, MaxDiffSize: 10})
+
+		require.NoError(t, err)
+		assert.Equal(t, giteaDiff[:10]+"\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ...", res.PR.RawDiff)
+	})
+
+	t.Run("Failure_Unauthorized", func(t *testing.T) {
+		_, err := stub.driver(t, "wrong-token").GetPullRequest(ctx, scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		var statusErr *scm.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+		assert.Equal(t, "get pull request", statusErr.Op)
+
+		retryable, _ := scm.ClassifyError(err)
+		assert.False(t, retryable)
+	})
+}
+
+func TestGiteaDriver_IssueComments(t *testing.T) {
+	ctx := context.Background()
+	stub := newGiteaStub(t)
+	driver := stub.driver(t, "fake-token")
+
+	first, second, updated := "first", "second", "updated"
+
+	t.Run("Success_PostUpdateListAndDelete", func(t *testing.T) {
+		require.NoError(t, driver.PostIssueComment(ctx, scm.PostIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{Body: &first}}))
+		require.NoError(t, driver.PostIssueComment(ctx, scm.PostIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{Body: &second}}))
+		require.NoError(t, driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{ID: 1, Body: &updated}}))
+		require.NoError(t, driver.DeleteIssueComment(ctx, scm.DeleteIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, CommentID: 2}))
+
+		res, err := driver.ListIssueComments(ctx, scm.ListIssueCommentsRequest{Owner: "owner", Repo: "repo", Number: 1})
+
+		require.NoError(t, err)
+		require.Len(t, res.Comments, 1)
+		assert.Equal(t, int64(1), res.Comments[0].ID)
+		assert.Equal(t, "updated", *res.Comments[0].Body)
+		assert.Equal(t, "elgtm-bot", res.Comments[0].Author)
+	})
+
+	t.Run("Failure_UpdateMissingComment", func(t *testing.T) {
+		err := driver.UpdateIssueComment(ctx, scm.UpdateIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{ID: 42, Body: &updated}})
+
+		assert.EqualError(t, err, "failed to update issue comment 42 with status: 404")
+	})
+
+	t.Run("Failure_DeleteMissingComment", func(t *testing.T) {
+		err := driver.DeleteIssueComment(ctx, scm.DeleteIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, CommentID: 42})
+
+		assert.EqualError(t, err, "failed to delete issue comment 42 with status: 404")
+	})
+
+	t.Run("Success_GetCurrentUser", func(t *testing.T) {
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "elgtm-bot", res.Author)
+	})
+}
+
+func TestGiteaDriver_PostReview(t *testing.T) {
+	ctx := context.Background()
+	body := "Summary"
+
+	t.Run("Success_PostReviewWithInlineComments", func(t *testing.T) {
+		stub := newGiteaStub(t)
+
+		err := stub.driver(t, "fake-token").PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "owner",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{
+				Body:     &body,
+				CommitID: "headsha",
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+					{Path: "util.go", Side: scm.SideRight, Start: &scm.DiffLine{NewLine: 3}, End: scm.DiffLine{NewLine: 5}, Body: "Multi line"},
+					{Path: "old.go", Side: scm.SideLeft, End: scm.DiffLine{OldLine: 7}, Body: "Removed line"},
+				},
+			},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, stub.reviews, 1)
+
+		review := stub.reviews[0]
+		assert.Equal(t, "Summary", review["body"])
+		assert.Equal(t, "headsha", review["commit_id"])
+		assert.Equal(t, []any{
+			map[string]any{"path": "main.go", "body": "Single line", "new_position": float64(10)},
+			map[string]any{"path": "util.go", "body": "Multi line", "new_position": float64(5)},
+			map[string]any{"path": "old.go", "body": "Removed line", "old_position": float64(7)},
+		}, review["comments"])
+	})
+
+	t.Run("Failure_FailedToCreateReview", func(t *testing.T) {
+		stub := newGiteaStub(t)
+
+		err := stub.driver(t, "wrong-token").PostReview(ctx, scm.PostReviewRequest{Owner: "owner", Repo: "repo", Number: 1, Review: &scm.Review{Body: &body}})
+
+		assert.EqualError(t, err, "failed to create pull request review with status: 401")
+	})
+}
+
+func TestGiteaDriver_CompareCommits(t *testing.T) {
+	driver, err := scm.NewGiteaDriver(&http.Client{}, "https://gitea.example.com", "fake-token")
+	require.NoError(t, err)
+
+	_, err = driver.CompareCommits(context.Background(), scm.CompareCommitsRequest{Owner: "owner", Repo: "repo", Base: "basesha", Head: "headsha"})
+
+	assert.ErrorIs(t, err, scm.ErrCompareUnsupported)
+	assert.NotErrorIs(t, err, scm.ErrCommitUnreachable)
+}
@@ -2,25 +2,56 @@ package scm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 
+	"github.com/fzl-22/elgtm/internal/diff"
 	"github.com/google/go-github/v82/github"
 )
 
+// githubActionsBot is the author of the comments posted with the GITHUB_TOKEN
+// of GitHub Actions.
+const githubActionsBot = "github-actions[bot]"
+
 type GitHubDriver struct {
 	client     *github.Client
 	httpClient *http.Client
+	// app is the transport of a driver authenticated as a GitHub App.
+	app *appTransport
+}
+
+// GitHubOption configures the SDK client of a GitHubDriver.
+type GitHubOption func(client *github.Client) (*github.Client, error)
+
+// WithEnterpriseURLs points the driver to the API of a GitHub Enterprise
+// Server instance, e.g. https://github.example.com/api/v3/. The upload URL
+// defaults to the base URL.
+func WithEnterpriseURLs(baseURL, uploadURL string) GitHubOption {
+	return func(client *github.Client) (*github.Client, error) {
+		if uploadURL == "" {
+			uploadURL = baseURL
+		}
+		return client.WithEnterpriseURLs(baseURL, uploadURL)
+	}
 }
 
-func NewGitHubDriver(httpClient *http.Client, token string) (*GitHubDriver, error) {
+func NewGitHubDriver(httpClient *http.Client, token string, opts ...GitHubOption) (*GitHubDriver, error) {
 	if token == "" {
 		return nil, fmt.Errorf("github token is missing")
 	}
 
+	client := github.NewClient(httpClient).WithAuthToken(token)
+	for _, opt := range opts {
+		var err error
+		if client, err = opt(client); err != nil {
+			return nil, fmt.Errorf("failed to initialize github driver: %w", err)
+		}
+	}
+
 	return &GitHubDriver{
-		client:     github.NewClient(httpClient).WithAuthToken(token),
+		client:     client,
 		httpClient: httpClient,
 	}, nil
 }
@@ -36,7 +67,11 @@ func (c *GitHubDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Authorization", fmt.Sprintf("token %s", req.Token))
+	// Drivers authenticated as a GitHub App have no token of their own: their
+	// transport authenticates the request.
+	if req.Token != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("token %s", req.Token))
+	}
 
 	res, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -45,7 +80,7 @@ func (c *GitHubDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get diff with status: %d", res.StatusCode)
+		return nil, newStatusError("get diff", res)
 	}
 
 	limitedReader := io.LimitReader(res.Body, req.MaxDiffSize)
@@ -55,9 +90,10 @@ func (c *GitHubDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 		return nil, fmt.Errorf("failed to read diff: %w", err)
 	}
 
+	files := diff.Parse(string(diffBytes))
+
 	if int64(len(diffBytes)) == req.MaxDiffSize {
-		truncationMessage := "\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ..."
-		diffBytes = append(diffBytes, []byte(truncationMessage)...)
+		diffBytes = append(diffBytes, []byte(diffTruncationMessage)...)
 	}
 
 	parsedPR := &PullRequest{
@@ -70,6 +106,9 @@ func (c *GitHubDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 		HTMLURL:   pr.GetHTMLURL(),
 		DiffURL:   pr.GetDiffURL(),
 		RawDiff:   string(diffBytes),
+		Files:     files,
+		BaseSHA:   pr.GetBase().GetSHA(),
+		HeadSHA:   pr.GetHead().GetSHA(),
 		CreatedAt: pr.GetCreatedAt().Time,
 		UpdatedAt: pr.GetUpdatedAt().Time,
 	}
@@ -90,3 +129,150 @@ func (c *GitHubDriver) PostIssueComment(ctx context.Context, req PostIssueCommen
 
 	return nil
 }
+
+func (c *GitHubDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var comments []*IssueComment
+	for {
+		page, resp, err := c.client.Issues.ListComments(ctx, req.Owner, req.Repo, req.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issue comments: %w", err)
+		}
+
+		for _, comment := range page {
+			comments = append(comments, &IssueComment{
+				ID:     comment.GetID(),
+				Body:   comment.Body,
+				Author: comment.GetUser().GetLogin(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return &ListIssueCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+func (c *GitHubDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	issueComment := github.IssueComment{
+		Body: req.IssueComment.Body,
+	}
+	_, _, err := c.client.Issues.EditComment(ctx, req.Owner, req.Repo, req.IssueComment.ID, &issueComment)
+	if err != nil {
+		return fmt.Errorf("failed to update issue comment %d: %w", req.IssueComment.ID, err)
+	}
+
+	return nil
+}
+
+func (c *GitHubDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	_, err := c.client.Issues.DeleteComment(ctx, req.Owner, req.Repo, req.CommentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete issue comment %d: %w", req.CommentID, err)
+	}
+
+	return nil
+}
+
+func (c *GitHubDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	comments := make([]*github.DraftReviewComment, 0, len(req.Review.Comments))
+	for _, comment := range req.Review.Comments {
+		draft := &github.DraftReviewComment{
+			Path: github.Ptr(comment.Path),
+			Body: github.Ptr(comment.Body),
+			Line: github.Ptr(comment.Line(comment.End)),
+			Side: github.Ptr(string(comment.Side)),
+		}
+
+		if comment.Start != nil {
+			draft.StartLine = github.Ptr(comment.Line(*comment.Start))
+			draft.StartSide = github.Ptr(string(comment.Side))
+		}
+
+		comments = append(comments, draft)
+	}
+
+	review := github.PullRequestReviewRequest{
+		Body:     req.Review.Body,
+		Event:    github.Ptr("COMMENT"),
+		Comments: comments,
+	}
+
+	if req.Review.CommitID != "" {
+		review.CommitID = github.Ptr(req.Review.CommitID)
+	}
+
+	_, _, err := c.client.PullRequests.CreateReview(ctx, req.Owner, req.Repo, req.Number, &review)
+	if err != nil {
+		return fmt.Errorf("failed to create pull request review: %w", err)
+	}
+
+	return nil
+}
+
+// CompareCommits returns the diff between two commits of the repository. The
+// base must be an ancestor of the head; otherwise the history was rewritten
+// and ErrCommitUnreachable is returned.
+func (c *GitHubDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(ctx, req.Owner, req.Repo, req.Base, req.Head, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, ErrCommitUnreachable)
+		}
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	switch comparison.GetStatus() {
+	case "ahead", "identical":
+	default:
+		return nil, fmt.Errorf("failed to compare %s...%s with status %q: %w", req.Base, req.Head, comparison.GetStatus(), ErrCommitUnreachable)
+	}
+
+	rawDiff, _, err := c.client.Repositories.CompareCommitsRaw(ctx, req.Owner, req.Repo, req.Base, req.Head, github.RawOptions{Type: github.Diff})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff for %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	rawDiff, files := truncateDiff(rawDiff, req.MaxDiffSize)
+
+	return &CompareCommitsResponse{
+		Comparison: &Comparison{
+			BaseSHA: req.Base,
+			HeadSHA: req.Head,
+			RawDiff: rawDiff,
+			Files:   files,
+		},
+	}, nil
+}
+
+// GetCurrentUser returns the login of the user the token belongs to. Apps have
+// no user of their own: a GitHub App comments as its bot, and GitHub Actions,
+// whose token cannot read the user, as github-actions[bot].
+func (c *GitHubDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	if c.app != nil {
+		slug, err := c.app.slug(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &GetCurrentUserResponse{Author: slug + "[bot]"}, nil
+	}
+
+	user, res, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusForbidden {
+			return &GetCurrentUserResponse{Author: githubActionsBot}, nil
+		}
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	return &GetCurrentUserResponse{Author: user.GetLogin()}, nil
+}
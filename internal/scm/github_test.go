@@ -2,9 +2,12 @@ package scm_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -49,6 +52,58 @@ func TestGitHubDriver_NewGitHubDriver(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, driver)
 	})
+
+	t.Run("Failure_InvalidEnterpriseURL", func(t *testing.T) {
+		driver, err := scm.NewGitHubDriver(httpClient, "fake-github-token", scm.WithEnterpriseURLs("://invalid-url", ""))
+
+		assert.ErrorContains(t, err, "failed to initialize github driver")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestGitHubDriver_EnterpriseServer(t *testing.T) {
+	ctx := context.Background()
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ghes/api/v3/repos/owner/repo/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer fake-token", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, `{"id": 12345, "number": 1, "title": "feat: ghes", "user": {"login": "fzl-22"}, "diff_url": "%s/ghes/owner/repo/pull/1.diff"}`, server.URL)
+	})
+	mux.HandleFunc("GET /ghes/owner/repo/pull/1.diff", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token fake-token", r.Header.Get("Authorization"))
+		fmt.Fprint(w, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n")
+	})
+	mux.HandleFunc("POST /ghes/api/v3/repos/owner/repo/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+
+	server = httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	httpClient, err := scm.NewHTTPClient(&http.Client{Timeout: 5 * time.Second}, writeCACertFile(t, server), false)
+	require.NoError(t, err)
+
+	driver, err := scm.NewGitHubDriver(httpClient, "fake-token", scm.WithEnterpriseURLs(server.URL+"/ghes/", ""))
+	require.NoError(t, err)
+
+	t.Run("Success_GetPullRequest", func(t *testing.T) {
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, Token: "fake-token", MaxDiffSize: 1024})
+
+		require.NoError(t, err)
+		assert.Equal(t, "feat: ghes", res.PR.Title)
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "main.go", res.PR.Files[0].Path())
+	})
+
+	t.Run("Success_PostIssueComment", func(t *testing.T) {
+		body := "LGTM"
+
+		err := driver.PostIssueComment(ctx, scm.PostIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, Token: "fake-token", IssueComment: &scm.IssueComment{Body: &body}})
+
+		assert.NoError(t, err)
+	})
 }
 
 func TestGitHubDriver_GetPullRequest(t *testing.T) {
@@ -118,6 +173,8 @@ func TestGitHubDriver_GetPullRequest(t *testing.T) {
 		assert.Equal(t, "https://github.com/fake/diff", res.PR.DiffURL)
 
 		assert.Equal(t, fakeDiffContent, res.PR.RawDiff)
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "main.go", res.PR.Files[0].Path())
 	})
 
 	t.Run("Success_GetPullRequestWithTruncation", func(t *testing.T) {
@@ -395,3 +452,424 @@ func TestGitHubDriver_PostIssueComment(t *testing.T) {
 		assert.Contains(t, err.Error(), "simulated network error")
 	})
 }
+
+func TestGitHubDriver_ListIssueComments(t *testing.T) {
+	ctx := context.Background()
+	req := scm.ListIssueCommentsRequest{
+		Owner:  "owner",
+		Repo:   "repo",
+		Number: 1,
+		Token:  "fake-token",
+	}
+
+	t.Run("Success_ListAllPages", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "GET", req.Method)
+				assert.Equal(t, "/repos/owner/repo/issues/1/comments", req.URL.Path)
+				assert.Equal(t, "100", req.URL.Query().Get("per_page"))
+
+				header := make(http.Header)
+				body := `[{"id": 2, "body": "second", "user": {"login": "bot"}}]`
+				if req.URL.Query().Get("page") == "" {
+					header.Set("Link", `<https://api.github.com/repos/owner/repo/issues/1/comments?page=2&per_page=100>; rel="next"`)
+					body = `[{"id": 1, "body": "first", "user": {"login": "fzl-22"}}]`
+				}
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     header,
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.ListIssueComments(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		require.Len(t, res.Comments, 2)
+		assert.Equal(t, int64(1), res.Comments[0].ID)
+		assert.Equal(t, "first", *res.Comments[0].Body)
+		assert.Equal(t, "fzl-22", res.Comments[0].Author)
+		assert.Equal(t, int64(2), res.Comments[1].ID)
+		assert.Equal(t, "bot", res.Comments[1].Author)
+	})
+
+	t.Run("Failure_FailedToListIssueComments", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("simulated network error")
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.ListIssueComments(ctx, req)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.Contains(t, err.Error(), "failed to list issue comments")
+	})
+}
+
+func TestGitHubDriver_UpdateIssueComment(t *testing.T) {
+	ctx := context.Background()
+	bodyStr := "Updated review"
+
+	req := scm.UpdateIssueCommentRequest{
+		Owner:        "owner",
+		Repo:         "repo",
+		Number:       1,
+		Token:        "fake-token",
+		IssueComment: &scm.IssueComment{ID: 42, Body: &bodyStr},
+	}
+
+	t.Run("Success_UpdateIssueComment", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "PATCH", req.Method)
+				assert.Equal(t, "/repos/owner/repo/issues/comments/42", req.URL.Path)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.UpdateIssueComment(ctx, req)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failure_FailedToUpdateIssueComment", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       io.NopCloser(strings.NewReader(`{"message": "Not Found"}`)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.UpdateIssueComment(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update issue comment 42")
+	})
+}
+
+func TestGitHubDriver_DeleteIssueComment(t *testing.T) {
+	ctx := context.Background()
+
+	req := scm.DeleteIssueCommentRequest{
+		Owner:     "owner",
+		Repo:      "repo",
+		Number:    1,
+		Token:     "fake-token",
+		CommentID: 42,
+	}
+
+	t.Run("Success_DeleteIssueComment", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "DELETE", req.Method)
+				assert.Equal(t, "/repos/owner/repo/issues/comments/42", req.URL.Path)
+
+				return &http.Response{
+					StatusCode: http.StatusNoContent,
+					Body:       io.NopCloser(strings.NewReader("")),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.DeleteIssueComment(ctx, req)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failure_FailedToDeleteIssueComment", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusForbidden,
+					Body:       io.NopCloser(strings.NewReader(`{"message": "Forbidden"}`)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.DeleteIssueComment(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete issue comment 42")
+	})
+}
+
+func TestGitHubDriver_PostReview(t *testing.T) {
+	ctx := context.Background()
+
+	req := scm.PostReviewRequest{
+		Owner:  "owner",
+		Repo:   "repo",
+		Number: 1,
+		Token:  "fake-token",
+		Review: &scm.Review{
+			CommitID: "abc123",
+			Comments: []*scm.ReviewComment{
+				{Path: "main.go", OldPath: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+				{Path: "util.go", OldPath: "util.go", Side: scm.SideLeft, Start: &scm.DiffLine{OldLine: 3}, End: scm.DiffLine{OldLine: 5, NewLine: 6}, Body: "Multi line"},
+			},
+		},
+	}
+
+	t.Run("Success_PostReview", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "/repos/owner/repo/pulls/1/reviews", req.URL.Path)
+
+				var payload struct {
+					CommitID string `json:"commit_id"`
+					Event    string `json:"event"`
+					Comments []struct {
+						Path      string  `json:"path"`
+						Body      string  `json:"body"`
+						Line      int     `json:"line"`
+						Side      string  `json:"side"`
+						StartLine *int    `json:"start_line"`
+						StartSide *string `json:"start_side"`
+					} `json:"comments"`
+				}
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+
+				assert.Equal(t, "abc123", payload.CommitID)
+				assert.Equal(t, "COMMENT", payload.Event)
+				require.Len(t, payload.Comments, 2)
+
+				assert.Equal(t, "main.go", payload.Comments[0].Path)
+				assert.Equal(t, 10, payload.Comments[0].Line)
+				assert.Equal(t, "RIGHT", payload.Comments[0].Side)
+				assert.Nil(t, payload.Comments[0].StartLine)
+				assert.Nil(t, payload.Comments[0].StartSide)
+
+				assert.Equal(t, "util.go", payload.Comments[1].Path)
+				assert.Equal(t, 5, payload.Comments[1].Line)
+				assert.Equal(t, "LEFT", payload.Comments[1].Side)
+				require.NotNil(t, payload.Comments[1].StartLine)
+				assert.Equal(t, 3, *payload.Comments[1].StartLine)
+				require.NotNil(t, payload.Comments[1].StartSide)
+				assert.Equal(t, "LEFT", *payload.Comments[1].StartSide)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.PostReview(ctx, req)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failure_FailedToPostReview", func(t *testing.T) {
+		transport := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusUnprocessableEntity,
+					Body:       io.NopCloser(strings.NewReader(`{"message": "Line could not be resolved"}`)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+
+		httpClient := &http.Client{Transport: transport}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		err = driver.PostReview(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create pull request review")
+	})
+}
+
+func TestGitHubDriver_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+	req := scm.CompareCommitsRequest{
+		Owner:       "owner",
+		Repo:        "repo",
+		Base:        "abc123",
+		Head:        "def456",
+		Token:       "fake-token",
+		MaxDiffSize: 1024,
+	}
+
+	newTransport := func(status int, body, rawDiff string) *mockRoundTripper {
+		return &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/repos/owner/repo/compare/abc123...def456", req.URL.Path)
+
+				if req.Header.Get("Accept") == "application/vnd.github.v3.diff" {
+					body = rawDiff
+				}
+
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+	}
+
+	t.Run("Success_CompareCommits", func(t *testing.T) {
+		rawDiff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"
+
+		httpClient := &http.Client{Transport: newTransport(http.StatusOK, `{"status": "ahead"}`, rawDiff)}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "abc123", res.Comparison.BaseSHA)
+		assert.Equal(t, "def456", res.Comparison.HeadSHA)
+		assert.Equal(t, rawDiff, res.Comparison.RawDiff)
+		require.Len(t, res.Comparison.Files, 1)
+		assert.Equal(t, "main.go", res.Comparison.Files[0].NewPath)
+	})
+
+	t.Run("Success_CompareCommitsWithTruncation", func(t *testing.T) {
+		truncReq := req
+		truncReq.MaxDiffSize = 10
+
+		httpClient := &http.Client{Transport: newTransport(http.StatusOK, `{"status": "ahead"}`, "diff --git a/main.go b/main.go\n")}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, truncReq)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "diff --git\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ...", res.Comparison.RawDiff)
+	})
+
+	t.Run("Failure_BaseDiverged", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusOK, `{"status": "diverged"}`, "")}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_BaseNotFound", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusNotFound, `{"message": "Not Found"}`, "")}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_FailedToCompareCommits", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusInternalServerError, `{"message": "Server Error"}`, "")}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, scm.ErrCommitUnreachable)
+		assert.Contains(t, err.Error(), "failed to compare abc123...def456")
+	})
+}
+
+func TestGitHubDriver_GetCurrentUser(t *testing.T) {
+	ctx := context.Background()
+
+	newTransport := func(status int, body string) *mockRoundTripper {
+		return &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/user", req.URL.Path)
+
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			},
+		}
+	}
+
+	t.Run("Success_GetCurrentUser", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusOK, `{"login": "elgtm-bot"}`)}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{Token: "token"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "elgtm-bot", res.Author)
+	})
+
+	t.Run("Success_GitHubActionsToken", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusForbidden, `{"message": "Resource not accessible by integration"}`)}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{Token: "token"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "github-actions[bot]", res.Author)
+	})
+
+	t.Run("Failure_FailedToGetCurrentUser", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newTransport(http.StatusUnauthorized, `{"message": "Bad credentials"}`)}
+		driver, err := scm.NewGitHubDriver(httpClient, "token")
+		require.NoError(t, err)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{Token: "token"})
+
+		assert.Nil(t, res)
+		assert.Contains(t, err.Error(), "failed to get current user")
+	})
+}
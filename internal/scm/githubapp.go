@@ -0,0 +1,279 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v82/github"
+)
+
+const (
+	// appJWTLifetime is how long the JWTs signed for the app are valid. GitHub
+	// accepts at most 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew backdates the JWTs to allow for a server clock running
+	// behind ours.
+	appJWTClockSkew = time.Minute
+	// installationTokenRefreshWindow is how long before it expires an
+	// installation token is replaced, so that a request never goes out with a
+	// token about to expire.
+	installationTokenRefreshWindow = 5 * time.Minute
+)
+
+// GitHubApp identifies the installation of a GitHub App a driver acts as.
+type GitHubApp struct {
+	ID int64
+	// InstallationID is the installation of the app on the repository, looked
+	// up from Owner and Repo when zero.
+	InstallationID int64
+	// PrivateKey is the PEM-encoded RSA private key of the app.
+	PrivateKey []byte
+	// Owner and Repo name the repository the installation tokens are
+	// restricted to.
+	Owner string
+	Repo  string
+}
+
+// NewGitHubAppDriver creates a GitHub driver authenticated as an installation
+// of a GitHub App rather than with a token. It exchanges a JWT signed with the
+// app's private key for an installation token on the first request, and for a
+// new one shortly before the token expires.
+func NewGitHubAppDriver(httpClient *http.Client, app GitHubApp, opts ...GitHubOption) (*GitHubDriver, error) {
+	if app.ID == 0 {
+		return nil, fmt.Errorf("github app ID is missing")
+	}
+
+	key, err := parseAppPrivateKey(app.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport := &appTransport{
+		base:           base,
+		appID:          app.ID,
+		installationID: app.InstallationID,
+		owner:          app.Owner,
+		repo:           app.Repo,
+		key:            key,
+	}
+
+	appClient := *httpClient
+	appClient.Transport = transport
+
+	client := github.NewClient(&appClient)
+	for _, opt := range opts {
+		if client, err = opt(client); err != nil {
+			return nil, fmt.Errorf("failed to initialize github driver: %w", err)
+		}
+	}
+	transport.baseURL = client.BaseURL
+
+	return &GitHubDriver{
+		client:     client,
+		httpClient: &appClient,
+		app:        transport,
+	}, nil
+}
+
+func parseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("github app private key is not PEM-encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse github app private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("github app private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// appTransport authenticates the requests it sends with an installation token
+// of a GitHub App.
+type appTransport struct {
+	base    http.RoundTripper
+	baseURL *url.URL
+
+	appID          int64
+	installationID int64
+	owner          string
+	repo           string
+	key            *rsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.installationToken(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+
+	return t.base.RoundTrip(req)
+}
+
+// installationToken returns the current installation token, creating a new
+// one when it is missing or about to expire.
+func (t *appTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expiresAt) > installationTokenRefreshWindow {
+		return t.token, nil
+	}
+
+	jwt, err := t.signJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if t.installationID == 0 {
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		path := fmt.Sprintf("repos/%s/%s/installation", url.PathEscape(t.owner), url.PathEscape(t.repo))
+		if err := t.do(ctx, http.MethodGet, path, jwt, nil, &installation, "find github app installation"); err != nil {
+			return "", err
+		}
+		t.installationID = installation.ID
+		slog.Debug("GitHub App installation found", "app_id", t.appID, "installation_id", t.installationID)
+	}
+
+	body := map[string][]string{"repositories": {t.repo}}
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("app/installations/%d/access_tokens", t.installationID)
+	if err := t.do(ctx, http.MethodPost, path, jwt, body, &token, "create github app installation token"); err != nil {
+		return "", err
+	}
+
+	t.token, t.expiresAt = token.Token, token.ExpiresAt
+	slog.Debug("GitHub App installation token created", "installation_id", t.installationID, "expires_at", t.expiresAt)
+
+	return t.token, nil
+}
+
+// slug returns the slug of the app, after which its bot user is named.
+func (t *appTransport) slug(ctx context.Context) (string, error) {
+	jwt, err := t.signJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := t.do(ctx, http.MethodGet, "app", jwt, nil, &app, "get github app"); err != nil {
+		return "", err
+	}
+
+	return app.Slug, nil
+}
+
+// do sends a request authenticated as the app itself, with a JWT.
+func (t *appTransport) do(ctx context.Context, method, path, jwt string, body, result any, op string) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", op, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL.JoinPath(path).String(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newStatusError(op, res)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	return nil
+}
+
+// signJWT returns a JWT identifying the app, signed with its private key with
+// RS256.
+func (t *appTransport) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign github app JWT: %w", err)
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
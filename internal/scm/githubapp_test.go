@@ -0,0 +1,239 @@
+package scm_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+// verifyAppJWT checks that the request is authenticated with a JWT of the app
+// signed with its private key.
+func verifyAppJWT(t *testing.T, r *http.Request, key *rsa.PrivateKey, appID string) {
+	t.Helper()
+
+	jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	require.True(t, ok, "missing JWT")
+
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, appID, claims.Iss)
+	assert.Less(t, claims.Iat, time.Now().Unix())
+	assert.LessOrEqual(t, claims.Exp-claims.Iat, int64((10 * time.Minute).Seconds()))
+}
+
+func TestGitHubAppDriver_NewGitHubAppDriver(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	_, privateKey := newAppKey(t)
+
+	t.Run("Success_PKCS1Key", func(t *testing.T) {
+		driver, err := scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{ID: 1, PrivateKey: privateKey})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Success_PKCS8Key", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+
+		driver, err := scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{ID: 1, PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingAppID", func(t *testing.T) {
+		driver, err := scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{PrivateKey: privateKey})
+
+		assert.EqualError(t, err, "github app ID is missing")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_KeyNotPEM", func(t *testing.T) {
+		driver, err := scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{ID: 1, PrivateKey: []byte("not a key")})
+
+		assert.EqualError(t, err, "github app private key is not PEM-encoded")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_KeyNotRSA", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+
+		driver, err := scm.NewGitHubAppDriver(httpClient, scm.GitHubApp{ID: 1, PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})})
+
+		assert.EqualError(t, err, "github app private key is not an RSA key")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestGitHubAppDriver_Authentication(t *testing.T) {
+	ctx := context.Background()
+	key, privateKey := newAppKey(t)
+	body := "LGTM"
+	commentReq := scm.PostIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{Body: &body}}
+
+	// newServer stubs the installation endpoints of a GitHub Enterprise
+	// Server, counting the installation lookups and the tokens created, which
+	// expire after tokenLifetime.
+	newServer := func(t *testing.T, tokenLifetime time.Duration) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+		t.Helper()
+		var lookups, tokens atomic.Int32
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v3/repos/owner/repo/installation", func(w http.ResponseWriter, r *http.Request) {
+			verifyAppJWT(t, r, key, "123")
+			lookups.Add(1)
+			fmt.Fprint(w, `{"id": 42}`)
+		})
+		mux.HandleFunc("POST /api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+			verifyAppJWT(t, r, key, "123")
+
+			var req struct {
+				Repositories []string `json:"repositories"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []string{"repo"}, req.Repositories)
+
+			n := tokens.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, n, time.Now().Add(tokenLifetime).Format(time.RFC3339))
+		})
+		mux.HandleFunc("GET /api/v3/app", func(w http.ResponseWriter, r *http.Request) {
+			verifyAppJWT(t, r, key, "123")
+			fmt.Fprint(w, `{"id": 123, "slug": "elgtm"}`)
+		})
+		mux.HandleFunc("POST /api/v3/repos/owner/repo/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, fmt.Sprintf("token ghs_%d", tokens.Load()), r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		return server, &lookups, &tokens
+	}
+
+	newDriver := func(t *testing.T, server *httptest.Server, installationID int64) *scm.GitHubDriver {
+		t.Helper()
+		driver, err := scm.NewGitHubAppDriver(&http.Client{Timeout: 5 * time.Second}, scm.GitHubApp{
+			ID:             123,
+			InstallationID: installationID,
+			PrivateKey:     privateKey,
+			Owner:          "owner",
+			Repo:           "repo",
+		}, scm.WithEnterpriseURLs(server.URL, ""))
+		require.NoError(t, err)
+		return driver
+	}
+
+	t.Run("Success_DiscoverInstallation", func(t *testing.T) {
+		server, lookups, tokens := newServer(t, time.Hour)
+		driver := newDriver(t, server, 0)
+
+		require.NoError(t, driver.PostIssueComment(ctx, commentReq))
+		require.NoError(t, driver.PostIssueComment(ctx, commentReq))
+
+		assert.Equal(t, int32(1), lookups.Load())
+		assert.Equal(t, int32(1), tokens.Load())
+	})
+
+	t.Run("Success_ConfiguredInstallation", func(t *testing.T) {
+		server, lookups, tokens := newServer(t, time.Hour)
+		driver := newDriver(t, server, 42)
+
+		require.NoError(t, driver.PostIssueComment(ctx, commentReq))
+
+		assert.Zero(t, lookups.Load())
+		assert.Equal(t, int32(1), tokens.Load())
+	})
+
+	t.Run("Success_RefreshTokenBeforeExpiry", func(t *testing.T) {
+		server, _, tokens := newServer(t, 2*time.Minute)
+		driver := newDriver(t, server, 42)
+
+		require.NoError(t, driver.PostIssueComment(ctx, commentReq))
+		require.NoError(t, driver.PostIssueComment(ctx, commentReq))
+
+		assert.Equal(t, int32(2), tokens.Load())
+	})
+
+	t.Run("Success_CurrentUserIsAppBot", func(t *testing.T) {
+		server, _, tokens := newServer(t, time.Hour)
+		driver := newDriver(t, server, 42)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "elgtm[bot]", res.Author)
+		assert.Zero(t, tokens.Load())
+	})
+
+	t.Run("Failure_TokenExchangeRejected", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver := newDriver(t, server, 42)
+
+		err := driver.PostIssueComment(ctx, commentReq)
+
+		var statusErr *scm.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+		assert.Equal(t, "create github app installation token", statusErr.Op)
+
+		retryable, _ := scm.ClassifyError(err)
+		assert.False(t, retryable)
+	})
+}
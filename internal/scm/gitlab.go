@@ -2,11 +2,13 @@ package scm
 
 import (
 	"context"
+	"crypto/sha1"
+	"errors"
 	"fmt"
-	"log/slog"
 	"path"
 	"strings"
 
+	"github.com/fzl-22/elgtm/internal/diff"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
@@ -14,8 +16,10 @@ type GitLabDriver struct {
 	client *gitlab.Client
 }
 
+// NewGitLabDriver creates a GitLab driver. The SDK's own retries are turned off
+// since the SCM client retries failed calls.
 func NewGitLabDriver(token string, opts ...gitlab.ClientOptionFunc) (*GitLabDriver, error) {
-	client, err := gitlab.NewClient(token, opts...)
+	client, err := gitlab.NewClient(token, append([]gitlab.ClientOptionFunc{gitlab.WithoutRetries()}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize gitlab driver: %w", err)
 	}
@@ -36,21 +40,25 @@ func (d *GitLabDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 		return nil, fmt.Errorf("failed to get merge request: %w", err)
 	}
 
-	diffs, _, err := d.client.MergeRequests.ListMergeRequestDiffs(projectPath, int64(req.Number), nil, gitlab.WithContext(ctx))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get diff for merge request #%d: %w", req.Number, err)
+	opts := &gitlab.ListMergeRequestDiffsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
 	}
 
-	var diffBuilder strings.Builder
-	for _, diff := range diffs {
-		if diffBuilder.Len()+len(diff.Diff) > int(req.MaxDiffSize) {
-			diffBuilder.WriteString("\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ...")
+	var diffs []*gitlab.MergeRequestDiff
+	for {
+		page, resp, err := d.client.MergeRequests.ListMergeRequestDiffs(projectPath, int64(req.Number), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get diff for merge request #%d: %w", req.Number, err)
+		}
+		diffs = append(diffs, page...)
+
+		if resp.NextPage == 0 {
 			break
 		}
-		diffBuilder.WriteString(diff.Diff)
+		opts.Page = resp.NextPage
 	}
 
-	slog.Info("DIFF", "diff", diffBuilder.String())
+	rawDiff, files := joinDiffs(diffs, req.MaxDiffSize)
 
 	parsedMR := &PullRequest{
 		ID:        mr.ID,
@@ -60,7 +68,10 @@ func (d *GitLabDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*G
 		Author:    mr.Author.Username,
 		URL:       mr.WebURL,
 		HTMLURL:   mr.WebURL,
-		RawDiff:   diffBuilder.String(),
+		RawDiff:   rawDiff,
+		Files:     files,
+		BaseSHA:   mr.DiffRefs.BaseSha,
+		HeadSHA:   mr.DiffRefs.HeadSha,
 		CreatedAt: *mr.CreatedAt,
 		UpdatedAt: *mr.UpdatedAt,
 	}
@@ -80,3 +91,286 @@ func (d *GitLabDriver) PostIssueComment(ctx context.Context, req PostIssueCommen
 
 	return nil
 }
+
+func (d *GitLabDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	projectPath := path.Join(req.Owner, req.Repo)
+	opts := &gitlab.ListMergeRequestNotesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	var comments []*IssueComment
+	for {
+		notes, resp, err := d.client.Notes.ListMergeRequestNotes(projectPath, int64(req.Number), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list merge request notes: %w", err)
+		}
+
+		for _, note := range notes {
+			// System notes record events such as pushes, not comments.
+			if note.System {
+				continue
+			}
+			comments = append(comments, &IssueComment{
+				ID:     note.ID,
+				Body:   gitlab.Ptr(note.Body),
+				Author: note.Author.Username,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return &ListIssueCommentsResponse{
+		Comments: comments,
+	}, nil
+}
+
+func (d *GitLabDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	projectPath := path.Join(req.Owner, req.Repo)
+	_, _, err := d.client.Notes.UpdateMergeRequestNote(projectPath, int64(req.Number), req.IssueComment.ID, &gitlab.UpdateMergeRequestNoteOptions{
+		Body: req.IssueComment.Body,
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update issue note %d: %w", req.IssueComment.ID, err)
+	}
+
+	return nil
+}
+
+func (d *GitLabDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	projectPath := path.Join(req.Owner, req.Repo)
+	_, err := d.client.Notes.DeleteMergeRequestNote(projectPath, int64(req.Number), req.CommentID, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete issue note %d: %w", req.CommentID, err)
+	}
+
+	return nil
+}
+
+// PostReview posts the review body as a merge request note and each comment
+// as a discussion positioned on the diff of the merge request's current
+// version.
+func (d *GitLabDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	projectPath := path.Join(req.Owner, req.Repo)
+
+	mr, _, err := d.client.MergeRequests.GetMergeRequest(projectPath, int64(req.Number), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to get merge request: %w", err)
+	}
+
+	if req.Review.Body != nil && *req.Review.Body != "" {
+		_, _, err := d.client.Notes.CreateMergeRequestNote(projectPath, int64(req.Number), &gitlab.CreateMergeRequestNoteOptions{
+			Body: req.Review.Body,
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to create review note: %w", err)
+		}
+	}
+
+	var errs []error
+	posted := req.Review.Body != nil && *req.Review.Body != ""
+	for _, comment := range req.Review.Comments {
+		_, _, err := d.client.Discussions.CreateMergeRequestDiscussion(projectPath, int64(req.Number), &gitlab.CreateMergeRequestDiscussionOptions{
+			Body:     gitlab.Ptr(comment.Body),
+			Position: discussionPosition(mr.DiffRefs, comment),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create discussion on %s:%d: %w", comment.Path, comment.Line(comment.End), err))
+			continue
+		}
+		posted = true
+	}
+
+	if len(errs) > 0 && posted {
+		return fmt.Errorf("%w: %w", errPartiallyPosted, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+func discussionPosition(refs gitlab.MergeRequestDiffRefs, comment *ReviewComment) *gitlab.PositionOptions {
+	position := &gitlab.PositionOptions{
+		BaseSHA:      gitlab.Ptr(refs.BaseSha),
+		StartSHA:     gitlab.Ptr(refs.StartSha),
+		HeadSHA:      gitlab.Ptr(refs.HeadSha),
+		PositionType: gitlab.Ptr("text"),
+		NewPath:      gitlab.Ptr(comment.Path),
+		OldPath:      gitlab.Ptr(comment.OldPath),
+	}
+
+	if comment.End.NewLine > 0 {
+		position.NewLine = gitlab.Ptr(int64(comment.End.NewLine))
+	}
+	if comment.End.OldLine > 0 {
+		position.OldLine = gitlab.Ptr(int64(comment.End.OldLine))
+	}
+
+	if comment.Start != nil {
+		position.LineRange = &gitlab.LineRangeOptions{
+			Start: linePosition(comment.Path, *comment.Start),
+			End:   linePosition(comment.Path, comment.End),
+		}
+	}
+
+	return position
+}
+
+// CompareCommits returns the diff between two commits of the project. The base
+// must be an ancestor of the head; otherwise the history was rewritten and
+// ErrCommitUnreachable is returned.
+func (d *GitLabDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	projectPath := path.Join(req.Owner, req.Repo)
+
+	mergeBase, _, err := d.client.Repositories.MergeBase(projectPath, &gitlab.MergeBaseOptions{
+		Ref: &[]string{req.Base, req.Head},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		if errors.Is(err, gitlab.ErrNotFound) {
+			return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", req.Base, req.Head, ErrCommitUnreachable)
+		}
+		return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", req.Base, req.Head, err)
+	}
+
+	if mergeBase.ID != req.Base {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, ErrCommitUnreachable)
+	}
+
+	compare, _, err := d.client.Repositories.Compare(projectPath, &gitlab.CompareOptions{
+		From:     gitlab.Ptr(req.Base),
+		To:       gitlab.Ptr(req.Head),
+		Straight: gitlab.Ptr(true),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	diffs := make([]*gitlab.MergeRequestDiff, 0, len(compare.Diffs))
+	for _, d := range compare.Diffs {
+		diffs = append(diffs, &gitlab.MergeRequestDiff{
+			OldPath:     d.OldPath,
+			NewPath:     d.NewPath,
+			AMode:       d.AMode,
+			BMode:       d.BMode,
+			Diff:        d.Diff,
+			NewFile:     d.NewFile,
+			RenamedFile: d.RenamedFile,
+			DeletedFile: d.DeletedFile,
+		})
+	}
+
+	rawDiff, files := joinDiffs(diffs, req.MaxDiffSize)
+
+	return &CompareCommitsResponse{
+		Comparison: &Comparison{
+			BaseSHA: req.Base,
+			HeadSHA: req.Head,
+			RawDiff: rawDiff,
+			Files:   files,
+		},
+	}, nil
+}
+
+// linePosition describes one end of a multi-line range. GitLab identifies the
+// line by its line code, the SHA-1 of the file path followed by the old and
+// new line numbers.
+func linePosition(filePath string, line DiffLine) *gitlab.LinePositionOptions {
+	pos := &gitlab.LinePositionOptions{
+		LineCode: gitlab.Ptr(fmt.Sprintf("%x_%d_%d", sha1.Sum([]byte(filePath)), line.OldLine, line.NewLine)),
+	}
+
+	if line.OldLine > 0 {
+		pos.OldLine = gitlab.Ptr(int64(line.OldLine))
+	}
+	if line.NewLine > 0 {
+		pos.NewLine = gitlab.Ptr(int64(line.NewLine))
+	}
+
+	switch {
+	case line.OldLine == 0:
+		pos.Type = gitlab.Ptr("new")
+	case line.NewLine == 0:
+		pos.Type = gitlab.Ptr("old")
+	}
+
+	return pos
+}
+
+// joinDiffs concatenates the per-file diffs returned by GitLab into a single
+// unified diff of at most maxSize bytes, dropping whole files that do not fit.
+func joinDiffs(diffs []*gitlab.MergeRequestDiff, maxSize int64) (string, []ChangedFile) {
+	var diffBuilder strings.Builder
+	truncated := false
+	for _, d := range diffs {
+		fileDiff := fileDiffHeader(d) + d.Diff
+		if !strings.HasSuffix(fileDiff, "\n") {
+			fileDiff += "\n"
+		}
+		if diffBuilder.Len()+len(fileDiff) > int(maxSize) {
+			truncated = true
+			break
+		}
+		diffBuilder.WriteString(fileDiff)
+	}
+
+	files := diff.Parse(diffBuilder.String())
+
+	if truncated {
+		diffBuilder.WriteString(diffTruncationMessage)
+	}
+
+	return diffBuilder.String(), files
+}
+
+// fileDiffHeader returns the git headers that GitLab omits from the per-file
+// diffs it returns, so that the concatenated diff says which file each hunk
+// belongs to and how the file changed.
+func fileDiffHeader(d *gitlab.MergeRequestDiff) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", d.OldPath, d.NewPath)
+
+	switch {
+	case d.NewFile:
+		fmt.Fprintf(&b, "new file mode %s\n", d.BMode)
+	case d.DeletedFile:
+		fmt.Fprintf(&b, "deleted file mode %s\n", d.AMode)
+	case d.AMode != d.BMode && d.AMode != "" && d.BMode != "":
+		fmt.Fprintf(&b, "old mode %s\nnew mode %s\n", d.AMode, d.BMode)
+	}
+
+	if d.RenamedFile {
+		fmt.Fprintf(&b, "rename from %s\nrename to %s\n", d.OldPath, d.NewPath)
+	}
+
+	// GitLab returns an empty diff for binary files and pure renames.
+	if d.Diff == "" {
+		return b.String()
+	}
+
+	oldPath := "a/" + d.OldPath
+	if d.NewFile {
+		oldPath = "/dev/null"
+	}
+
+	newPath := "b/" + d.NewPath
+	if d.DeletedFile {
+		newPath = "/dev/null"
+	}
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldPath, newPath)
+
+	return b.String()
+}
+
+// GetCurrentUser returns the username of the user the token belongs to, which
+// is a bot user for project and group access tokens.
+func (d *GitLabDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	user, _, err := d.client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	return &GetCurrentUserResponse{Author: user.Username}, nil
+}
@@ -1,10 +1,18 @@
 package scm_test
 
 import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/fzl-22/elgtm/internal/scm"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
@@ -26,3 +34,589 @@ func TestGitLabDriver_NewGitLabDriver(t *testing.T) {
 		assert.Nil(t, driver)
 	})
 }
+
+func TestGitLabDriver_SelfManaged(t *testing.T) {
+	mux := newGitLabMux()
+	mux.HandleFunc("/gitlab/api/v4/projects/owner/repo/merge_requests/1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fake-token", r.Header.Get("Private-Token"))
+		fmt.Fprint(w, `{"id": 12345, "iid": 1, "title": "feat: self-managed", "author": {"username": "fzl-22"}, "created_at": "2024-01-01T12:00:00Z", "updated_at": "2024-01-01T12:00:00Z"}`)
+	})
+	mux.HandleFunc("/gitlab/api/v4/projects/owner/repo/merge_requests/1/diffs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"old_path": "main.go", "new_path": "main.go", "diff": "@@ -1 +1 @@\n-old\n+new\n"}]`)
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	t.Run("Success_GetPullRequestWithInsecureSkipVerify", func(t *testing.T) {
+		httpClient, err := scm.NewHTTPClient(&http.Client{}, "", true)
+		require.NoError(t, err)
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithHTTPClient(httpClient), gitlab.WithBaseURL(server.URL+"/gitlab"))
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(context.Background(), scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		require.NoError(t, err)
+		assert.Equal(t, "feat: self-managed", res.PR.Title)
+		require.Len(t, res.PR.Files, 1)
+	})
+
+	t.Run("Failure_UntrustedCertificate", func(t *testing.T) {
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL+"/gitlab"))
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(context.Background(), scm.GetPRRequest{Owner: "owner", Repo: "repo", Number: 1, MaxDiffSize: 1024})
+
+		assert.ErrorContains(t, err, "certificate")
+		assert.Nil(t, res)
+	})
+}
+
+// gitLabMux routes requests by their escaped path, since the client sends the
+// project path URL-encoded (e.g. "owner%2Frepo").
+type gitLabMux map[string]http.HandlerFunc
+
+func newGitLabMux() gitLabMux {
+	return gitLabMux{}
+}
+
+func (m gitLabMux) HandleFunc(pattern string, handler http.HandlerFunc) {
+	m[strings.Replace(pattern, "owner/repo", "owner%2Frepo", 1)] = handler
+}
+
+func (m gitLabMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := m[r.URL.EscapedPath()]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+func TestGitLabDriver_GetPullRequest(t *testing.T) {
+	ctx := context.Background()
+	req := scm.GetPRRequest{
+		Owner:       "owner",
+		Repo:        "repo",
+		Number:      1,
+		Token:       "fake-token",
+		MaxDiffSize: 1024,
+	}
+
+	t.Run("Success_GetMergeRequestWithFileHeaders", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{
+				"id": 12345,
+				"iid": 1,
+				"title": "feat: add ai review",
+				"description": "This is a test MR",
+				"author": {"username": "fzl-22"},
+				"web_url": "https://gitlab.com/owner/repo/-/merge_requests/1",
+				"diff_refs": {"base_sha": "base", "head_sha": "head", "start_sha": "start"},
+				"created_at": "2024-01-01T12:00:00Z",
+				"updated_at": "2024-01-01T12:00:00Z"
+			}`)
+		})
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/diffs", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[
+				{"old_path": "main.go", "new_path": "main.go", "a_mode": "100644", "b_mode": "100644", "diff": "@@ -1 +1 @@\n-old\n+new\n"},
+				{"old_path": "new.go", "new_path": "new.go", "a_mode": "0", "b_mode": "100644", "new_file": true, "diff": "@@ -0,0 +1 @@\n+package main"},
+				{"old_path": "old.png", "new_path": "img.png", "a_mode": "100644", "b_mode": "100644", "renamed_file": true, "diff": ""}
+			]`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, int64(12345), res.PR.ID)
+		assert.Equal(t, 1, res.PR.Number)
+		assert.Equal(t, "fzl-22", res.PR.Author)
+		assert.Equal(t, "base", res.PR.BaseSHA)
+		assert.Equal(t, "head", res.PR.HeadSHA)
+		assert.Equal(t, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n"+
+			"diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+package main\n"+
+			"diff --git a/old.png b/img.png\nrename from old.png\nrename to img.png\n", res.PR.RawDiff)
+
+		require.Len(t, res.PR.Files, 3)
+		assert.Equal(t, "main.go", res.PR.Files[0].Path())
+		require.Len(t, res.PR.Files[0].Hunks, 1)
+		assert.Len(t, res.PR.Files[0].Hunks[0].Lines, 2)
+		assert.True(t, res.PR.Files[1].IsNew)
+		assert.Equal(t, "new.go", res.PR.Files[1].Path())
+		assert.True(t, res.PR.Files[2].IsRenamed)
+		assert.Equal(t, "old.png", res.PR.Files[2].OldPath)
+		assert.Equal(t, "img.png", res.PR.Files[2].NewPath)
+		assert.Empty(t, res.PR.Files[2].Hunks)
+	})
+
+	t.Run("Success_GetMergeRequestWithAllDiffPages", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": 12345, "iid": 1, "author": {"username": "fzl-22"}, "created_at": "2024-01-01T12:00:00Z", "updated_at": "2024-01-01T12:00:00Z"}`)
+		})
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/diffs", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"old_path": "a.go", "new_path": "a.go", "diff": "@@ -1 +1 @@\n-old\n+new\n"}]`)
+				return
+			}
+			assert.Equal(t, "2", r.URL.Query().Get("page"))
+			fmt.Fprint(w, `[{"old_path": "b.go", "new_path": "b.go", "diff": "@@ -1 +1 @@\n-old\n+new\n"}]`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		require.Len(t, res.PR.Files, 2)
+		assert.Equal(t, "a.go", res.PR.Files[0].Path())
+		assert.Equal(t, "b.go", res.PR.Files[1].Path())
+	})
+
+	t.Run("Success_GetMergeRequestWithTruncation", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": 12345, "iid": 1, "author": {"username": "fzl-22"}, "created_at": "2024-01-01T12:00:00Z", "updated_at": "2024-01-01T12:00:00Z"}`)
+		})
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/diffs", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[
+				{"old_path": "a.go", "new_path": "a.go", "diff": "@@ -1 +1 @@\n-old\n+new\n"},
+				{"old_path": "b.go", "new_path": "b.go", "diff": "@@ -1 +1 @@\n-old\n+new\n"}
+			]`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		truncatedReq := req
+		truncatedReq.MaxDiffSize = 100
+		res, err := driver.GetPullRequest(ctx, truncatedReq)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, strings.HasSuffix(res.PR.RawDiff, "\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ..."))
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "a.go", res.PR.Files[0].Path())
+	})
+}
+
+func TestGitLabDriver_PostReview(t *testing.T) {
+	ctx := context.Background()
+
+	mrHandler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 12345, "iid": 1, "diff_refs": {"base_sha": "base", "head_sha": "head", "start_sha": "start"}}`)
+	}
+
+	t.Run("Success_PostPositionedDiscussions", func(t *testing.T) {
+		var positions []map[string]any
+
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", mrHandler)
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/discussions", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+
+			var payload struct {
+				Body     string         `json:"body"`
+				Position map[string]any `json:"position"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			positions = append(positions, payload.Position)
+
+			fmt.Fprint(w, `{"id": "abc"}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "owner",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", OldPath: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+					{Path: "util.go", OldPath: "util.go", Side: scm.SideRight, Start: &scm.DiffLine{OldLine: 3, NewLine: 4}, End: scm.DiffLine{NewLine: 5}, Body: "Multi line"},
+				},
+			},
+		})
+
+		assert.NoError(t, err)
+		require.Len(t, positions, 2)
+
+		assert.Equal(t, "base", positions[0]["base_sha"])
+		assert.Equal(t, "start", positions[0]["start_sha"])
+		assert.Equal(t, "head", positions[0]["head_sha"])
+		assert.Equal(t, "text", positions[0]["position_type"])
+		assert.Equal(t, "main.go", positions[0]["new_path"])
+		assert.Equal(t, "main.go", positions[0]["old_path"])
+		assert.Equal(t, float64(10), positions[0]["new_line"])
+		assert.NotContains(t, positions[0], "old_line")
+		assert.NotContains(t, positions[0], "line_range")
+
+		lineRange := positions[1]["line_range"].(map[string]any)
+		start := lineRange["start"].(map[string]any)
+		end := lineRange["end"].(map[string]any)
+		assert.Equal(t, fmt.Sprintf("%x_3_4", sha1.Sum([]byte("util.go"))), start["line_code"])
+		assert.NotContains(t, start, "type")
+		assert.Equal(t, fmt.Sprintf("%x_0_5", sha1.Sum([]byte("util.go"))), end["line_code"])
+		assert.Equal(t, "new", end["type"])
+		assert.Equal(t, float64(5), positions[1]["new_line"])
+	})
+
+	t.Run("Success_PostReviewBodyAsNote", func(t *testing.T) {
+		var noteBody string
+
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", mrHandler)
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/notes", func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Body string `json:"body"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			noteBody = payload.Body
+
+			fmt.Fprint(w, `{"id": 1}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		body := "Review summary"
+		err = driver.PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "owner",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{Body: &body},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Review summary", noteBody)
+	})
+
+	t.Run("Failure_FailedToGetMergeRequest", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.PostReview(ctx, scm.PostReviewRequest{Owner: "owner", Repo: "repo", Number: 1, Review: &scm.Review{}})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get merge request")
+	})
+
+	t.Run("Failure_FailedToCreateDiscussion", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1", mrHandler)
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/discussions", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message": "400 Bad request - Note {:line_code=>[\"can't be blank\"]}"}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.PostReview(ctx, scm.PostReviewRequest{
+			Owner:  "owner",
+			Repo:   "repo",
+			Number: 1,
+			Review: &scm.Review{
+				Comments: []*scm.ReviewComment{
+					{Path: "main.go", OldPath: "main.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 10}, Body: "Single line"},
+				},
+			},
+		})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create discussion on main.go:10")
+	})
+}
+
+func TestGitLabDriver_ListIssueComments(t *testing.T) {
+	ctx := context.Background()
+	req := scm.ListIssueCommentsRequest{Owner: "owner", Repo: "repo", Number: 1}
+
+	t.Run("Success_ListAllPagesSkippingSystemNotes", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/notes", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"id": 1, "body": "first", "author": {"username": "fzl-22"}}, {"id": 2, "body": "added 1 commit", "system": true}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"id": 3, "body": "third", "author": {"username": "bot"}}]`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.ListIssueComments(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		require.Len(t, res.Comments, 2)
+		assert.Equal(t, int64(1), res.Comments[0].ID)
+		assert.Equal(t, "first", *res.Comments[0].Body)
+		assert.Equal(t, "fzl-22", res.Comments[0].Author)
+		assert.Equal(t, int64(3), res.Comments[1].ID)
+	})
+
+	t.Run("Failure_FailedToListNotes", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.ListIssueComments(ctx, req)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.Contains(t, err.Error(), "failed to list merge request notes")
+	})
+}
+
+func TestGitLabDriver_GetCurrentUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_GetCurrentUser", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": 7, "username": "project_1_bot"}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "project_1_bot", res.Author)
+	})
+
+	t.Run("Failure_FailedToGetCurrentUser", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.GetCurrentUser(ctx, scm.GetCurrentUserRequest{})
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.Contains(t, err.Error(), "failed to get current user")
+	})
+}
+
+func TestGitLabDriver_UpdateIssueComment(t *testing.T) {
+	ctx := context.Background()
+	body := "Updated review"
+	req := scm.UpdateIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, IssueComment: &scm.IssueComment{ID: 42, Body: &body}}
+
+	t.Run("Success_UpdateNote", func(t *testing.T) {
+		var payload struct {
+			Body string `json:"body"`
+		}
+
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/notes/42", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPut, r.Method)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			fmt.Fprint(w, `{"id": 42}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.UpdateIssueComment(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated review", payload.Body)
+	})
+
+	t.Run("Failure_FailedToUpdateNote", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.UpdateIssueComment(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update issue note 42")
+	})
+}
+
+func TestGitLabDriver_DeleteIssueComment(t *testing.T) {
+	ctx := context.Background()
+	req := scm.DeleteIssueCommentRequest{Owner: "owner", Repo: "repo", Number: 1, CommentID: 42}
+
+	t.Run("Success_DeleteNote", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/merge_requests/1/notes/42", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.DeleteIssueComment(ctx, req)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failure_FailedToDeleteNote", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		err = driver.DeleteIssueComment(ctx, req)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete issue note 42")
+	})
+}
+
+func TestGitLabDriver_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+	req := scm.CompareCommitsRequest{
+		Owner:       "owner",
+		Repo:        "repo",
+		Base:        "abc123",
+		Head:        "def456",
+		Token:       "fake-token",
+		MaxDiffSize: 1024,
+	}
+
+	t.Run("Success_CompareCommits", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/repository/merge_base", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, []string{"abc123", "def456"}, r.URL.Query()["refs[]"])
+			fmt.Fprint(w, `{"id": "abc123"}`)
+		})
+		mux.HandleFunc("/api/v4/projects/owner/repo/repository/compare", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "abc123", r.URL.Query().Get("from"))
+			assert.Equal(t, "def456", r.URL.Query().Get("to"))
+			assert.Equal(t, "true", r.URL.Query().Get("straight"))
+			fmt.Fprint(w, `{"diffs": [
+				{"old_path": "main.go", "new_path": "main.go", "a_mode": "100644", "b_mode": "100644", "diff": "@@ -1 +1 @@\n-old\n+new\n"}
+			]}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "abc123", res.Comparison.BaseSHA)
+		assert.Equal(t, "def456", res.Comparison.HeadSHA)
+		assert.Equal(t, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n", res.Comparison.RawDiff)
+		require.Len(t, res.Comparison.Files, 1)
+		assert.Equal(t, "main.go", res.Comparison.Files[0].Path())
+	})
+
+	t.Run("Failure_BaseNotAncestor", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/repository/merge_base", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": "other"}`)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_BaseNotFound", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+	})
+
+	t.Run("Failure_FailedToCompare", func(t *testing.T) {
+		mux := newGitLabMux()
+		mux.HandleFunc("/api/v4/projects/owner/repo/repository/merge_base", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"id": "abc123"}`)
+		})
+		mux.HandleFunc("/api/v4/projects/owner/repo/repository/compare", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		driver, err := scm.NewGitLabDriver("fake-token", gitlab.WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, req)
+
+		assert.Nil(t, res)
+		assert.NotErrorIs(t, err, scm.ErrCommitUnreachable)
+		assert.Contains(t, err.Error(), "failed to compare abc123...def456")
+	})
+}
@@ -0,0 +1,260 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+)
+
+// LocalDriver reviews changes in a local git repository instead of a pull
+// request on an SCM platform. The pull request is built from the diff between
+// two refs, or from the staged changes, and everything that would be posted is
+// written to out instead.
+type LocalDriver struct {
+	dir    string
+	base   string
+	head   string
+	staged bool
+	out    io.Writer
+}
+
+// NewLocalDriver creates a driver for the git repository at dir that reviews
+// the changes of head since it diverged from base, like `git diff base...head`,
+// or the staged changes when staged is set.
+func NewLocalDriver(dir, base, head string, staged bool, out io.Writer) (*LocalDriver, error) {
+	if !staged && (base == "" || head == "") {
+		return nil, fmt.Errorf("base and head refs are required to review local changes")
+	}
+
+	d := &LocalDriver{
+		dir:    dir,
+		base:   base,
+		head:   head,
+		staged: staged,
+		out:    out,
+	}
+
+	if _, err := d.git(context.Background(), "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("not a git repository [%s]: %w", dir, err)
+	}
+
+	return d, nil
+}
+
+func (d *LocalDriver) GetPullRequest(ctx context.Context, req GetPRRequest) (*GetPRResponse, error) {
+	pr := &PullRequest{
+		Number: req.Number,
+		Author: d.author(ctx),
+	}
+
+	var rawDiff string
+	var err error
+	if d.staged {
+		pr.Title = "Staged changes"
+		// HEAD does not exist yet in a repository without commits.
+		pr.BaseSHA, _ = d.revParse(ctx, "HEAD")
+
+		rawDiff, err = d.git(ctx, "diff", "--cached", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get staged diff: %w", err)
+		}
+	} else {
+		pr.HeadSHA, err = d.revParse(ctx, d.head)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeBase, err := d.git(ctx, "merge-base", "--end-of-options", d.base, pr.HeadSHA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", d.base, d.head, err)
+		}
+		pr.BaseSHA = strings.TrimSpace(mergeBase)
+
+		pr.Title, pr.Body, err = d.describe(ctx, pr.BaseSHA, pr.HeadSHA)
+		if err != nil {
+			return nil, err
+		}
+
+		rawDiff, err = d.git(ctx, "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--end-of-options", pr.BaseSHA, pr.HeadSHA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get diff for %s...%s: %w", d.base, d.head, err)
+		}
+	}
+
+	pr.RawDiff, pr.Files = truncateDiff(rawDiff, req.MaxDiffSize)
+
+	return &GetPRResponse{
+		PR: pr,
+	}, nil
+}
+
+func (d *LocalDriver) PostIssueComment(ctx context.Context, req PostIssueCommentRequest) error {
+	if req.IssueComment.Body == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(d.out, "%s\n", *req.IssueComment.Body); err != nil {
+		return fmt.Errorf("failed to write comment: %w", err)
+	}
+	return nil
+}
+
+// ListIssueComments returns no comments, since local reviews are not kept
+// between runs.
+func (d *LocalDriver) ListIssueComments(ctx context.Context, req ListIssueCommentsRequest) (*ListIssueCommentsResponse, error) {
+	return &ListIssueCommentsResponse{}, nil
+}
+
+func (d *LocalDriver) UpdateIssueComment(ctx context.Context, req UpdateIssueCommentRequest) error {
+	return d.PostIssueComment(ctx, PostIssueCommentRequest{IssueComment: req.IssueComment})
+}
+
+func (d *LocalDriver) DeleteIssueComment(ctx context.Context, req DeleteIssueCommentRequest) error {
+	return nil
+}
+
+func (d *LocalDriver) PostReview(ctx context.Context, req PostReviewRequest) error {
+	var b strings.Builder
+	for _, comment := range req.Review.Comments {
+		location := fmt.Sprintf("%s:%d", comment.Path, comment.Line(comment.End))
+		if comment.Start != nil {
+			location = fmt.Sprintf("%s:%d-%d", comment.Path, comment.Line(*comment.Start), comment.Line(comment.End))
+		}
+		if comment.Side == SideLeft {
+			location += " (old)"
+		}
+
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", location, comment.Body)
+	}
+
+	if _, err := io.WriteString(d.out, b.String()); err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
+	return nil
+}
+
+// CompareCommits returns the diff between two commits of the repository. The
+// base must be an ancestor of the head; otherwise ErrCommitUnreachable is
+// returned.
+func (d *LocalDriver) CompareCommits(ctx context.Context, req CompareCommitsRequest) (*CompareCommitsResponse, error) {
+	if _, err := d.git(ctx, "merge-base", "--is-ancestor", "--end-of-options", req.Base, req.Head); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, ErrCommitUnreachable)
+		}
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	rawDiff, err := d.git(ctx, "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--end-of-options", req.Base, req.Head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff for %s...%s: %w", req.Base, req.Head, err)
+	}
+
+	rawDiff, files := truncateDiff(rawDiff, req.MaxDiffSize)
+
+	return &CompareCommitsResponse{
+		Comparison: &Comparison{
+			BaseSHA: req.Base,
+			HeadSHA: req.Head,
+			RawDiff: rawDiff,
+			Files:   files,
+		},
+	}, nil
+}
+
+// describe builds the title and body of the pull request from its commit
+// messages: a single commit provides both, several commits are listed in the
+// body under the subject of the first one.
+func (d *LocalDriver) describe(ctx context.Context, base, head string) (string, string, error) {
+	log, err := d.git(ctx, "log", "--reverse", "--format=%s%x1f%b%x1e", "--end-of-options", base+".."+head)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list commits of %s...%s: %w", d.base, d.head, err)
+	}
+
+	var subjects, bodies []string
+	for _, entry := range strings.Split(log, "\x1e") {
+		subject, body, ok := strings.Cut(strings.TrimSpace(entry), "\x1f")
+		if !ok {
+			continue
+		}
+		subjects = append(subjects, subject)
+		bodies = append(bodies, strings.TrimSpace(body))
+	}
+
+	switch len(subjects) {
+	case 0:
+		return fmt.Sprintf("%s...%s", d.base, d.head), "", nil
+	case 1:
+		return subjects[0], bodies[0], nil
+	}
+
+	var b strings.Builder
+	for _, subject := range subjects {
+		fmt.Fprintf(&b, "* %s\n", subject)
+	}
+	return subjects[0], strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// author returns the user configured in git, which is who is about to open
+// the pull request.
+func (d *LocalDriver) author(ctx context.Context) string {
+	for _, key := range []string{"user.name", "user.email"} {
+		if value, err := d.git(ctx, "config", "--get", key); err == nil && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func (d *LocalDriver) revParse(ctx context.Context, ref string) (string, error) {
+	sha, err := d.git(ctx, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(sha), nil
+}
+
+func (d *LocalDriver) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", d.dir}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
+
+// truncateDiff cuts the diff to the maximum diff size, marking it as
+// truncated, and parses the files it changes.
+func truncateDiff(rawDiff string, maxDiffSize int64) (string, []ChangedFile) {
+	if int64(len(rawDiff)) > maxDiffSize {
+		rawDiff = rawDiff[:maxDiffSize]
+	}
+
+	files := diff.Parse(rawDiff)
+
+	if int64(len(rawDiff)) == maxDiffSize {
+		rawDiff += diffTruncationMessage
+	}
+
+	return rawDiff, files
+}
+
+// GetCurrentUser returns the user configured in git, although no comment is
+// ever listed.
+func (d *LocalDriver) GetCurrentUser(ctx context.Context, req GetCurrentUserRequest) (*GetCurrentUserResponse, error) {
+	return &GetCurrentUserResponse{Author: d.author(ctx)}, nil
+}
@@ -0,0 +1,297 @@
+package scm_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitRepo creates a repository with a commit on main and the given commits
+// on a feature branch, each adding a file named after its subject.
+func newGitRepo(t *testing.T, commits ...string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "-q", "-b", "main")
+	git("config", "user.name", "Jane Doe")
+	git("config", "user.email", "jane@example.com")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Project\n"), 0644))
+	git("add", ".")
+	git("commit", "-q", "-m", "Initial commit")
+
+	git("checkout", "-q", "-b", "feature")
+	for _, commit := range commits {
+		subject, _, _ := strings.Cut(commit, "\n")
+		name := strings.ReplaceAll(strings.ToLower(subject), " ", "_") + ".go"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0644))
+		git("add", ".")
+		git("commit", "-q", "-m", commit)
+	}
+
+	return dir
+}
+
+func TestLocalDriver_NewLocalDriver(t *testing.T) {
+	t.Run("Success_InitDriver", func(t *testing.T) {
+		dir := newGitRepo(t)
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, driver)
+	})
+
+	t.Run("Failure_MissingRefs", func(t *testing.T) {
+		driver, err := scm.NewLocalDriver(".", "", "HEAD", false, &bytes.Buffer{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "base and head refs are required")
+		assert.Nil(t, driver)
+	})
+
+	t.Run("Failure_NotARepository", func(t *testing.T) {
+		newGitRepo(t)
+
+		driver, err := scm.NewLocalDriver(t.TempDir(), "main", "HEAD", false, &bytes.Buffer{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not a git repository")
+		assert.Nil(t, driver)
+	})
+}
+
+func TestLocalDriver_GetPullRequest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_SingleCommit", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser\n\nParses the input.")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		require.NoError(t, err)
+		pr := res.PR
+		assert.Equal(t, "Add parser", pr.Title)
+		assert.Equal(t, "Parses the input.", pr.Body)
+		assert.Equal(t, "Jane Doe", pr.Author)
+		assert.Len(t, pr.BaseSHA, 40)
+		assert.Len(t, pr.HeadSHA, 40)
+		assert.Contains(t, pr.RawDiff, "+++ b/add_parser.go")
+		require.Len(t, pr.Files, 1)
+		assert.Equal(t, "add_parser.go", pr.Files[0].Path())
+	})
+
+	t.Run("Success_SeveralCommits", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser", "Add lexer")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "feature", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Add parser", res.PR.Title)
+		assert.Equal(t, "* Add parser\n* Add lexer", res.PR.Body)
+		assert.Len(t, res.PR.Files, 2)
+	})
+
+	t.Run("Success_StagedChanges", func(t *testing.T) {
+		dir := newGitRepo(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "staged.go"), []byte("package main\n"), 0644))
+		require.NoError(t, exec.Command("git", "-C", dir, "add", "staged.go").Run())
+
+		driver, err := scm.NewLocalDriver(dir, "", "", true, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Staged changes", res.PR.Title)
+		assert.Len(t, res.PR.BaseSHA, 40)
+		assert.Empty(t, res.PR.HeadSHA)
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "staged.go", res.PR.Files[0].Path())
+	})
+
+	t.Run("Success_TruncateDiff", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 20})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(res.PR.RawDiff, "... [DIFF TRUNCATED DUE TO SIZE LIMIT] ..."))
+	})
+
+	t.Run("Success_IgnorePrefixConfig", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser")
+		require.NoError(t, exec.Command("git", "-C", dir, "config", "diff.noprefix", "true").Run())
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		require.NoError(t, err)
+		assert.Contains(t, res.PR.RawDiff, "diff --git a/add_parser.go b/add_parser.go")
+		require.Len(t, res.PR.Files, 1)
+		assert.Equal(t, "add_parser.go", res.PR.Files[0].Path())
+	})
+
+	t.Run("Failure_RefLooksLikeOption", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser")
+		output := filepath.Join(t.TempDir(), "output")
+
+		driver, err := scm.NewLocalDriver(dir, "--output="+output, "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to find merge base")
+		assert.Nil(t, res)
+		assert.NoFileExists(t, output)
+	})
+
+	t.Run("Failure_UnknownRef", func(t *testing.T) {
+		dir := newGitRepo(t)
+
+		driver, err := scm.NewLocalDriver(dir, "main", "no-such-branch", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.GetPullRequest(ctx, scm.GetPRRequest{MaxDiffSize: 1 << 20})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to resolve no-such-branch")
+		assert.Nil(t, res)
+	})
+}
+
+func TestLocalDriver_PostIssueComment(t *testing.T) {
+	dir := newGitRepo(t)
+	var out bytes.Buffer
+
+	driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &out)
+	require.NoError(t, err)
+
+	body := "Looks good."
+	err = driver.PostIssueComment(context.Background(), scm.PostIssueCommentRequest{
+		IssueComment: &scm.IssueComment{Body: &body},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Looks good.\n", out.String())
+}
+
+func TestLocalDriver_PostReview(t *testing.T) {
+	dir := newGitRepo(t)
+	var out bytes.Buffer
+
+	driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &out)
+	require.NoError(t, err)
+
+	err = driver.PostReview(context.Background(), scm.PostReviewRequest{
+		Review: &scm.Review{
+			Comments: []*scm.ReviewComment{
+				{Path: "a.go", Side: scm.SideRight, End: scm.DiffLine{NewLine: 3}, Body: "Wrap this line."},
+				{Path: "b.go", Side: scm.SideLeft, Start: &scm.DiffLine{OldLine: 1}, End: scm.DiffLine{OldLine: 2}, Body: "Keep these."},
+			},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "## a.go:3\n\nWrap this line.\n\n## b.go:1-2 (old)\n\nKeep these.\n\n", out.String())
+}
+
+func TestLocalDriver_ListIssueComments(t *testing.T) {
+	dir := newGitRepo(t)
+
+	driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	res, err := driver.ListIssueComments(context.Background(), scm.ListIssueCommentsRequest{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.Comments)
+}
+
+func TestLocalDriver_GetCurrentUser(t *testing.T) {
+	dir := newGitRepo(t)
+
+	driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	res, err := driver.GetCurrentUser(context.Background(), scm.GetCurrentUserRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", res.Author)
+}
+
+func TestLocalDriver_CompareCommits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success_CompareCommits", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser", "Add lexer")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, scm.CompareCommitsRequest{Base: "HEAD~1", Head: "HEAD", MaxDiffSize: 1 << 20})
+
+		require.NoError(t, err)
+		require.Len(t, res.Comparison.Files, 1)
+		assert.Equal(t, "add_lexer.go", res.Comparison.Files[0].Path())
+	})
+
+	t.Run("Failure_CommitUnreachable", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser", "Add lexer")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, scm.CompareCommitsRequest{Base: "HEAD", Head: "HEAD~1", MaxDiffSize: 1 << 20})
+
+		assert.ErrorIs(t, err, scm.ErrCommitUnreachable)
+		assert.Nil(t, res)
+	})
+
+	t.Run("Failure_RefLooksLikeOption", func(t *testing.T) {
+		dir := newGitRepo(t, "Add parser")
+
+		driver, err := scm.NewLocalDriver(dir, "main", "HEAD", false, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		res, err := driver.CompareCommits(ctx, scm.CompareCommitsRequest{Base: "--all", Head: "HEAD", MaxDiffSize: 1 << 20})
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, scm.ErrCommitUnreachable)
+		assert.Nil(t, res)
+	})
+}
@@ -0,0 +1,90 @@
+package scm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// restClient sends the requests of the drivers whose platform has no SDK. Its
+// failures are *StatusError, classified for retries like those of the SDKs.
+type restClient struct {
+	httpClient *http.Client
+	// authorize sets the credentials of each request.
+	authorize func(req *http.Request)
+}
+
+// request sends a JSON request and decodes the response into result, when not
+// nil.
+func (c *restClient) request(ctx context.Context, op, method, requestURL string, body, result any) error {
+	res, err := c.send(ctx, op, method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	return nil
+}
+
+// diff reads the unified diff at diffURL, up to maxDiffSize bytes.
+func (c *restClient) diff(ctx context.Context, diffURL string, maxDiffSize int64) (string, []ChangedFile, error) {
+	res, err := c.send(ctx, "get diff", http.MethodGet, diffURL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+
+	diffBytes, err := io.ReadAll(io.LimitReader(res.Body, maxDiffSize))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	rawDiff, files := truncateDiff(string(diffBytes), maxDiffSize)
+	return rawDiff, files, nil
+}
+
+// send sends an authenticated request and returns the response when it
+// succeeded, or a *StatusError otherwise.
+func (c *restClient) send(ctx context.Context, op, method, requestURL string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s: %w", op, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	c.authorize(req)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, newStatusError(op, res)
+	}
+
+	return res, nil
+}
@@ -7,4 +7,10 @@ import (
 type Client interface {
 	GetPullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error)
 	PostIssueComment(ctx context.Context, owner, repo string, number int, issueComent *IssueComment) error
+	ListIssueComments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error)
+	UpdateIssueComment(ctx context.Context, owner, repo string, number int, issueComment *IssueComment) error
+	DeleteIssueComment(ctx context.Context, owner, repo string, number int, commentID int64) error
+	PostReview(ctx context.Context, owner, repo string, number int, review *Review) error
+	CompareCommits(ctx context.Context, owner, repo, base, head string) (*Comparison, error)
+	GetCurrentUser(ctx context.Context) (string, error)
 }
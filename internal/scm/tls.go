@@ -0,0 +1,46 @@
+package scm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// NewHTTPClient returns a copy of httpClient for a self-hosted instance whose
+// certificate is signed by the CA certificates in caCertFile, trusted along
+// with the system ones, or is not verified at all when insecureSkipVerify is
+// set. httpClient is returned as is when neither is given.
+func NewHTTPClient(httpClient *http.Client, caCertFile string, insecureSkipVerify bool) (*http.Client, error) {
+	if caCertFile == "" && !insecureSkipVerify {
+		return httpClient, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caCertFile != "" {
+		pem, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in [%s]", caCertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	client := *httpClient
+	client.Transport = transport
+	return &client, nil
+}
@@ -0,0 +1,88 @@
+package scm_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/scm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCACertFile writes the certificate of a TLS test server to a PEM file.
+func writeCACertFile(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, certPEM, 0644))
+
+	return path
+}
+
+func TestHTTPClient_NewHTTPClient(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Run("Success_UnchangedWithoutOptions", func(t *testing.T) {
+		client, err := scm.NewHTTPClient(httpClient, "", false)
+
+		assert.NoError(t, err)
+		assert.Same(t, httpClient, client)
+	})
+
+	t.Run("Success_TrustCACertFile", func(t *testing.T) {
+		client, err := scm.NewHTTPClient(httpClient, writeCACertFile(t, server), false)
+		require.NoError(t, err)
+
+		res, err := client.Get(server.URL)
+
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, httpClient.Timeout, client.Timeout)
+	})
+
+	t.Run("Success_InsecureSkipVerify", func(t *testing.T) {
+		client, err := scm.NewHTTPClient(httpClient, "", true)
+		require.NoError(t, err)
+
+		res, err := client.Get(server.URL)
+
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	})
+
+	t.Run("Failure_UntrustedCertificate", func(t *testing.T) {
+		_, err := httpClient.Get(server.URL)
+
+		assert.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("Failure_MissingCACertFile", func(t *testing.T) {
+		client, err := scm.NewHTTPClient(httpClient, filepath.Join(t.TempDir(), "missing.pem"), false)
+
+		assert.ErrorContains(t, err, "failed to read CA certificates")
+		assert.Nil(t, client)
+	})
+
+	t.Run("Failure_NoCertificatesInFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0644))
+
+		client, err := scm.NewHTTPClient(httpClient, path, false)
+
+		assert.ErrorContains(t, err, "no CA certificates found")
+		assert.Nil(t, client)
+	})
+}
@@ -1,6 +1,16 @@
 package scm
 
-import "time"
+import (
+	"time"
+
+	"github.com/fzl-22/elgtm/internal/diff"
+)
+
+// diffTruncationMessage is appended to diffs cut short by the maximum diff size.
+const diffTruncationMessage = "\n\n... [DIFF TRUNCATED DUE TO SIZE LIMIT] ..."
+
+// ChangedFile is a file changed by the pull request, as parsed from its diff.
+type ChangedFile = diff.File
 
 type PullRequest struct {
 	ID        int64
@@ -12,10 +22,65 @@ type PullRequest struct {
 	HTMLURL   string
 	DiffURL   string
 	RawDiff   string
+	Files     []ChangedFile
+	BaseSHA   string
+	HeadSHA   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// Comparison is the diff between two commits, used to review only the changes
+// pushed since an earlier review.
+type Comparison struct {
+	BaseSHA string
+	HeadSHA string
+	RawDiff string
+	Files   []ChangedFile
+}
+
 type IssueComment struct {
+	ID   int64
 	Body *string
+	// Author identifies the author of the comment: a login, or an ID on
+	// platforms where logins are not unique.
+	Author string
+}
+
+type DiffSide string
+
+const (
+	SideLeft  DiffSide = "LEFT"
+	SideRight DiffSide = "RIGHT"
+)
+
+// DiffLine locates a line of the diff in the old and new versions of the file.
+// OldLine is zero for added lines and NewLine is zero for removed lines.
+type DiffLine struct {
+	OldLine int
+	NewLine int
+}
+
+// ReviewComment is a comment anchored to a line of the pull request diff, or
+// to a range of lines when Start is set.
+type ReviewComment struct {
+	Path    string
+	OldPath string
+	Side    DiffSide
+	Start   *DiffLine
+	End     DiffLine
+	Body    string
+}
+
+// Line returns the line number of the given position on the comment's side.
+func (c *ReviewComment) Line(pos DiffLine) int {
+	if c.Side == SideLeft {
+		return pos.OldLine
+	}
+	return pos.NewLine
+}
+
+type Review struct {
+	CommitID string
+	Body     *string
+	Comments []*ReviewComment
 }